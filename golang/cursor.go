@@ -6,24 +6,35 @@ import (
 )
 
 const (
-	AssignmentScope = cursor.AssignmentScope
-	BlockScope      = cursor.BlockScope
-	CommentScope    = cursor.CommentScope
-	ConstScope      = cursor.ConstScope
-	DeferScope      = cursor.DeferScope
-	DocScope        = cursor.DocScope
-	ExprScope       = cursor.ExprScope
-	FileScope       = cursor.FileScope
-	FuncDeclScope   = cursor.FuncDeclScope
-	IdentScope      = cursor.IdentScope
-	ImportPathScope = cursor.ImportPathScope
-	ImportScope     = cursor.ImportScope
-	PackageScope    = cursor.PackageScope
-	ReturnScope     = cursor.ReturnScope
-	SelectorScope   = cursor.SelectorScope
-	StringScope     = cursor.StringScope
-	TypeDeclScope   = cursor.TypeDeclScope
-	VarScope        = cursor.VarScope
+	AssignmentScope    = cursor.AssignmentScope
+	BlockScope         = cursor.BlockScope
+	ChanScope          = cursor.ChanScope
+	CommentScope       = cursor.CommentScope
+	CompositeLitScope  = cursor.CompositeLitScope
+	ConstScope         = cursor.ConstScope
+	ConstraintScope    = cursor.ConstraintScope
+	DeferScope         = cursor.DeferScope
+	DocScope           = cursor.DocScope
+	EmbedScope         = cursor.EmbedScope
+	ExprScope          = cursor.ExprScope
+	FileScope          = cursor.FileScope
+	FormatStringScope  = cursor.FormatStringScope
+	FuncDeclScope      = cursor.FuncDeclScope
+	FuncLitScope       = cursor.FuncLitScope
+	GoScope            = cursor.GoScope
+	IdentScope         = cursor.IdentScope
+	ImportPathScope    = cursor.ImportPathScope
+	ImportScope        = cursor.ImportScope
+	InterfaceBodyScope = cursor.InterfaceBodyScope
+	PackageScope       = cursor.PackageScope
+	RangeScope         = cursor.RangeScope
+	ReturnScope        = cursor.ReturnScope
+	SelectorScope      = cursor.SelectorScope
+	StmtStartScope     = cursor.StmtStartScope
+	StringScope        = cursor.StringScope
+	TypeDeclScope      = cursor.TypeDeclScope
+	TypeParamScope     = cursor.TypeParamScope
+	VarScope           = cursor.VarScope
 )
 
 type CursorScope = cursor.CurScope
@@ -0,0 +1,106 @@
+package cursor
+
+import (
+	"bytes"
+	"go/ast"
+	"go/scanner"
+	"go/token"
+)
+
+// ChanOpKind describes the kind of channel operation, and the operand, the cursor is on
+type ChanOpKind uint8
+
+const (
+	// UnknownChanOp is the zero value, the cursor is not in a channel operation
+	UnknownChanOp ChanOpKind = iota
+
+	// SendChanOp is the channel operand of a send statement e.g. `ch‸ <- v`
+	SendChanOp
+
+	// SendValueChanOp is the value operand of a send statement e.g. `ch <- v‸`
+	SendValueChanOp
+
+	// RecvChanOp is the channel operand of a receive expression e.g. `<-ch‸`
+	RecvChanOp
+)
+
+// IsSend returns true if k is the channel or value operand of a send statement
+func (k ChanOpKind) IsSend() bool {
+	return k == SendChanOp || k == SendValueChanOp
+}
+
+// ChannelOp returns the innermost channel operation enclosing the cursor.
+// kind reports whether it's a send or receive, and which operand the cursor is on.
+// valueExpr is nil for receive expressions and, while typing, either operand might be nil.
+//
+// If ok is true, the cursor is in ChanScope.
+func (cx *CurCtx) ChannelOp() (kind ChanOpKind, chanExpr, valueExpr ast.Expr, ok bool) {
+	for i := len(cx.Nodes) - 1; i >= 0; i-- {
+		switch x := cx.Nodes[i].(type) {
+		case *ast.SendStmt:
+			if cx.TokenPos > x.Arrow {
+				return SendValueChanOp, x.Chan, badExprNil(x.Value), true
+			}
+			return SendChanOp, x.Chan, badExprNil(x.Value), true
+		case *ast.UnaryExpr:
+			if x.Op == token.ARROW {
+				return RecvChanOp, badExprNil(x.X), nil, true
+			}
+		case *ast.FuncLit, ast.Stmt:
+			return UnknownChanOp, nil, nil, false
+		}
+	}
+	return UnknownChanOp, nil, nil, false
+}
+
+// SelectCommContext returns whether the cursor is in the header of a select statement's clause,
+// i.e. between `case` and `:`, where a send or receive operation is expected e.g. `select { case ‸: }`.
+// For the `default` clause, isDefault is true and expectsOp is false because it takes no operation.
+// ok is false if the cursor is in a clause body or not in a select statement.
+func (cx *CurCtx) SelectCommContext() (isDefault bool, expectsOp bool, ok bool) {
+	var cc *ast.CommClause
+	if !cx.Set(&cc) {
+		return false, false, false
+	}
+	start := cx.TokenFile.Offset(cc.Case)
+	if cx.srcPos < start {
+		return false, false, false
+	}
+	kw := token.CASE
+	if cc.Comm == nil && bytes.HasPrefix(cx.Src[start:], []byte(token.DEFAULT.String())) {
+		kw = token.DEFAULT
+	}
+	start += len(kw.String())
+	if cx.srcPos < start {
+		return false, false, false
+	}
+
+	// the clause may be incomplete, so look for the colon in the source instead of using cc.Colon
+	src := cx.Src[start:cx.srcPos]
+	var sc scanner.Scanner
+	sc.Init(token.NewFileSet().AddFile("", -1, len(src)), src, nil, 0)
+	depth := 0
+	for {
+		_, tok, _ := sc.Scan()
+		switch tok {
+		case token.EOF:
+			return kw == token.DEFAULT, kw == token.CASE, true
+		case token.LPAREN, token.LBRACK, token.LBRACE:
+			depth++
+		case token.RPAREN, token.RBRACK, token.RBRACE:
+			depth--
+		case token.COLON:
+			if depth <= 0 {
+				return false, false, false
+			}
+		}
+	}
+}
+
+// badExprNil returns nil if x is an *ast.BadExpr, otherwise x
+func badExprNil(x ast.Expr) ast.Expr {
+	if _, ok := x.(*ast.BadExpr); ok {
+		return nil
+	}
+	return x
+}
@@ -0,0 +1,70 @@
+package cursor
+
+import (
+	"testing"
+)
+
+func TestChannelOp(t *testing.T) {
+	cases := []struct {
+		src   string
+		kind  ChanOpKind
+		ch    string
+		value string
+	}{
+		{"package p\nfunc f() {\n\tc‸h <- v\n}\n", SendChanOp, "ch", "v"},
+		{"package p\nfunc f() {\n\tch <- v‸\n}\n", SendValueChanOp, "ch", "v"},
+		{"package p\nfunc f() {\n\tch <- ‸\n}\n", SendValueChanOp, "ch", ""},
+		{"package p\nfunc f() {\n\tv := <-c‸h\n}\n", RecvChanOp, "ch", ""},
+		{"package p\nfunc f() {\n\tch <- <-i‸n\n}\n", RecvChanOp, "in", ""},
+		{"package p\nfunc f() {\n\tv := c‸h\n}\n", UnknownChanOp, "", ""},
+		{"package p\nfunc f() {\n\tch <- func() int {\n\t\treturn ‸1\n\t}()\n}\n", UnknownChanOp, "", ""},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		kind, ch, value, ok := cx.ChannelOp()
+		if kind != c.kind || ok != (c.kind != UnknownChanOp) {
+			t.Errorf("ChannelOp() = (%v, %v), want (%v, %v) in %q", kind, ok, c.kind, c.kind != UnknownChanOp, c.src)
+		}
+		if ok != cx.Scope.Is(ChanScope) {
+			t.Errorf("Scope.Is(ChanScope) = %v, want %v in %q", cx.Scope.Is(ChanScope), ok, c.src)
+		}
+		chStr, valueStr := "", ""
+		if ch != nil {
+			chStr, _ = cx.Print(ch)
+		}
+		if value != nil {
+			valueStr, _ = cx.Print(value)
+		}
+		if chStr != c.ch || valueStr != c.value {
+			t.Errorf("ChannelOp() operands = (`%s`, `%s`), want (`%s`, `%s`) in %q", chStr, valueStr, c.ch, c.value, c.src)
+		}
+	}
+}
+
+func TestSelectCommContext(t *testing.T) {
+	cases := []struct {
+		src       string
+		isDefault bool
+		expectsOp bool
+		ok        bool
+	}{
+		{"case ‸:", false, true, true},
+		{"case ‸", false, true, true},
+		{"case <-‸:", false, true, true},
+		{"case v := <-chs[‸1:]:", false, true, true},
+		{"case ch <- v‸:", false, true, true},
+		{"default‸:", true, false, true},
+		{"case v := <-ch:\n\t\t‸", false, false, false},
+		{"case v := <-ch:\n\t\tx := v\n\t\t‸", false, false, false},
+		{"case <-ch: ‸", false, false, false},
+		{"default:\n\t\t‸", false, false, false},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\tselect {\n\t" + c.src + "\n\t}\n}\n"
+		cx := newTestCurCtx(t, src)
+		isDefault, expectsOp, ok := cx.SelectCommContext()
+		if isDefault != c.isDefault || expectsOp != c.expectsOp || ok != c.ok {
+			t.Errorf("SelectCommContext() = (%v, %v, %v), want (%v, %v, %v) in %q", isDefault, expectsOp, ok, c.isDefault, c.expectsOp, c.ok, c.src)
+		}
+	}
+}
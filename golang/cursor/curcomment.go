@@ -0,0 +1,175 @@
+package cursor
+
+import (
+	"go/ast"
+	"strings"
+)
+
+const (
+	embedDirective = "//go:embed"
+)
+
+// EnclosingBlockComment returns the `/* ... */` comment enclosing the cursor.
+// Line comments are ignored, as is `/*` inside strings, which is not a comment.
+func (cx *CurCtx) EnclosingBlockComment() (*ast.Comment, bool) {
+	c := cx.Comment
+	if c == nil || !strings.HasPrefix(c.Text, "/*") {
+		return nil, false
+	}
+	// the cursor is after the comment e.g. `/* x */‸`
+	if strings.HasSuffix(c.Text, "*/") && cx.srcPos >= cx.TokenFile.Offset(c.End()) {
+		return nil, false
+	}
+	return c, true
+}
+
+// EmbedPattern returns the partial pattern before the cursor e.g. `files/*.t`
+// in `//go:embed a.txt files/*.t‸`.
+// ok is true iff the cursor is in the arguments of a `//go:embed` directive (EmbedScope).
+//
+// Quoted patterns are supported; the opening quote is not included in prefix.
+func (cx *CurCtx) EmbedPattern() (prefix string, ok bool) {
+	if !cx.Scope.Is(EmbedScope) {
+		return "", false
+	}
+	return cx.embedPattern()
+}
+
+func (cx *CurCtx) embedPattern() (prefix string, ok bool) {
+	c := cx.Comment
+	if c == nil || !strings.HasPrefix(c.Text, embedDirective) {
+		return "", false
+	}
+	args := c.Text[len(embedDirective):]
+	if args != "" && args[0] != ' ' && args[0] != '\t' {
+		// e.g. `//go:embedded`
+		return "", false
+	}
+	n := cx.srcPos - cx.TokenFile.Offset(c.Pos()) - len(embedDirective)
+	if n < 1 || n > len(args) {
+		return "", false
+	}
+
+	start, quote := -1, byte(0)
+	for i := 0; i < n; i++ {
+		switch ch := args[i]; {
+		case quote != 0:
+			if ch == quote {
+				start, quote = -1, 0
+			}
+		case ch == ' ' || ch == '\t':
+			start = -1
+		case start < 0 && (ch == '"' || ch == '`'):
+			start, quote = i+1, ch
+		case start < 0:
+			start = i
+		}
+	}
+	if start < 0 {
+		return "", true
+	}
+	return args[start:n], true
+}
+
+// DocReferenceCandidates returns the names that the doc comment the cursor is in may refer to:
+// the receiver, type params, params and named results of a documented function,
+// or the fields or methods of a documented struct or interface type.
+// Blank (`_`) names are omitted.
+//
+// ok is false if the cursor is not in DocScope, or the documented declaration is not a function or type.
+func (cx *CurCtx) DocReferenceCandidates() ([]string, bool) {
+	if cx.Doc == nil {
+		return nil, false
+	}
+	names := []string{}
+	add := func(fls ...*ast.FieldList) {
+		for _, fl := range fls {
+			if fl == nil {
+				continue
+			}
+			for _, f := range fl.List {
+				for _, id := range f.Names {
+					if id.Name != "_" {
+						names = append(names, id.Name)
+					}
+				}
+			}
+		}
+	}
+	var ts *ast.TypeSpec
+	switch x := cx.Doc.Node.(type) {
+	case *ast.FuncDecl:
+		add(x.Recv)
+		for _, id := range receiverTypeParams(x) {
+			names = append(names, id.Name)
+		}
+		add(x.Type.TypeParams, x.Type.Params, x.Type.Results)
+		return names, true
+	case *ast.TypeSpec:
+		ts = x
+	case *ast.GenDecl:
+		if len(x.Specs) == 1 {
+			ts, _ = x.Specs[0].(*ast.TypeSpec)
+		}
+	}
+	if ts == nil {
+		return nil, false
+	}
+	add(ts.TypeParams)
+	switch x := ts.Type.(type) {
+	case *ast.StructType:
+		add(x.Fields)
+	case *ast.InterfaceType:
+		add(x.Methods)
+	}
+	return names, true
+}
+
+// DeprecationNote returns the text of the `Deprecated:` paragraph in the doc comment of the declaration
+// enclosing the cursor, or of the doc comment the cursor is in,
+// e.g. `Use G instead.` for `// Deprecated: Use G instead.`
+//
+// Declarations are checked from the innermost outwards, so a deprecated field or spec takes precedence
+// over its struct or group, but the deprecation of a group applies to all its specs.
+func (cx *CurCtx) DeprecationNote() (text string, ok bool) {
+	if cx.Doc != nil {
+		if text, ok := deprecationNote(&cx.Doc.CommentGroup); ok {
+			return text, true
+		}
+	}
+	for i := len(cx.Nodes) - 1; i >= 0; i-- {
+		var doc *ast.CommentGroup
+		switch x := cx.Nodes[i].(type) {
+		case *ast.Field:
+			doc = x.Doc
+		case *ast.ValueSpec:
+			doc = x.Doc
+		case *ast.TypeSpec:
+			doc = x.Doc
+		case *ast.GenDecl:
+			doc = x.Doc
+		case *ast.FuncDecl:
+			doc = x.Doc
+		}
+		if text, ok := deprecationNote(doc); ok {
+			return text, true
+		}
+	}
+	return "", false
+}
+
+// deprecationNote returns the text of the paragraph in doc starting with `Deprecated:`
+func deprecationNote(doc *ast.CommentGroup) (string, bool) {
+	if doc == nil {
+		return "", false
+	}
+	for _, para := range strings.Split(doc.Text(), "\n\n") {
+		para = strings.TrimSpace(para)
+		if !strings.HasPrefix(para, "Deprecated:") {
+			continue
+		}
+		text := strings.Fields(para[len("Deprecated:"):])
+		return strings.Join(text, " "), true
+	}
+	return "", false
+}
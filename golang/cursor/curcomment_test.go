@@ -0,0 +1,112 @@
+package cursor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEmbedPattern(t *testing.T) {
+	cases := []struct {
+		src    string
+		prefix string
+		ok     bool
+	}{
+		{"package p\n\n//go:embed files/*.t‸\nvar fs embed.FS\n", "files/*.t", true},
+		{"package p\n\n//go:embed ‸\nvar fs embed.FS\n", "", true},
+		{"package p\n\n//go:embed a.txt b/‸\nvar fs embed.FS\n", "b/", true},
+		{"package p\n\n//go:embed a.txt ‸ b.txt\nvar fs embed.FS\n", "", true},
+		{"package p\n\n//go:embed \"with space/a‸\nvar fs embed.FS\n", "with space/a", true},
+		{"package p\n\n//go:embed `x y` \"a b‸\"\nvar fs embed.FS\n", "a b", true},
+		{"package p\n\n//go:emb‸ed a.txt\nvar fs embed.FS\n", "", false},
+		{"package p\n\n//go:embedded a‸\nvar fs embed.FS\n", "", false},
+		{"package p\n\n// files/*.t‸\nvar fs embed.FS\n", "", false},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		prefix, ok := cx.EmbedPattern()
+		if prefix != c.prefix || ok != c.ok {
+			t.Errorf("EmbedPattern() = (%q, %v), want (%q, %v) in %q", prefix, ok, c.prefix, c.ok, c.src)
+		}
+		if got := cx.Scope.Is(EmbedScope); got != c.ok {
+			t.Errorf("Scope.Is(EmbedScope) = %v, want %v in %q", got, c.ok, c.src)
+		}
+	}
+}
+
+func TestDocReferenceCandidates(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"// Open opens ‸\nfunc Open(name string, flag int) (f *File, err error) {}\n", "name flag f err"},
+		{"// Get returns ‸\nfunc (m *Map[K, V]) Get(k K, _ bool) V {}\n", "m K V k"},
+		{"// Map maps ‸\nfunc Map[T, U any](l []T) []U {}\n", "T U l"},
+		{"// Server serves ‸\ntype Server struct {\n\tAddr string\n\tio.Writer\n\ta, b int\n}\n", "Addr a b"},
+		{"// Reader reads ‸\ntype Reader interface {\n\tRead(p []byte) (int, error)\n}\n", "Read"},
+		{"// N is ‸\nvar N int\n", "-"},
+		{"func f(a int) {\n\t// ‸\n}\n", "-"},
+	}
+	for _, c := range cases {
+		src := "package p\n" + c.src
+		cx := newTestCurCtx(t, src)
+		names, ok := cx.DocReferenceCandidates()
+		got := "-"
+		if ok {
+			got = strings.Join(names, " ")
+		}
+		if got != c.want {
+			t.Errorf("DocReferenceCandidates() = %q, want %q in %q", got, c.want, c.src)
+		}
+	}
+}
+
+func TestDeprecationNote(t *testing.T) {
+	cases := []struct {
+		src  string
+		text string
+		ok   bool
+	}{
+		{"package p\n// F does things.\n//\n// Deprecated: Use G\n// instead.\nfunc F() {\n\t‸\n}\n", "Use G instead.", true},
+		{"package p\n// F does things.\n//\n// Deprecated: Use G‸.\nfunc F() {\n}\n", "Use G.", true},
+		{"package p\n// F does things.\n// Deprecated: not a paragraph.\nfunc F() {\n\t‸\n}\n", "", false},
+		{"package p\n// Deprecated: the whole group.\nconst (\n\tA = ‸1\n)\n", "the whole group.", true},
+		{"package p\ntype T struct {\n\t// Deprecated: use M.\n\tN ‸int\n\tM int\n}\n", "use M.", true},
+		{"package p\ntype T struct {\n\t// Deprecated: use M.\n\tN int\n\tM ‸int\n}\n", "", false},
+		{"package p\nfunc F() {\n\t‸\n}\n", "", false},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		text, ok := cx.DeprecationNote()
+		if text != c.text || ok != c.ok {
+			t.Errorf("DeprecationNote() = (%q, %v), want (%q, %v) in %q", text, ok, c.text, c.ok, c.src)
+		}
+	}
+}
+
+func TestEnclosingBlockComment(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"/* x‸ */", "/* x */"},
+		{"/*\n\tx‸\n*/", "/*\n\tx\n*/"},
+		{"x := 1 /* x‸ */", "/* x */"},
+		{"/* x */‸", ""},
+		{"// x‸", ""},
+		{"// /* x‸", ""},
+		{"s := \"/* x‸\"", ""},
+		{"s := `/* x‸`", ""},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		cmt, ok := cx.EnclosingBlockComment()
+		got := ""
+		if cmt != nil {
+			got = cmt.Text
+		}
+		if got != c.want || ok != (c.want != "") {
+			t.Errorf("EnclosingBlockComment() = (%q, %v), want (%q, %v) in %q", got, ok, c.want, c.want != "", c.src)
+		}
+	}
+}
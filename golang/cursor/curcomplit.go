@@ -0,0 +1,1001 @@
+package cursor
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/scanner"
+	"go/token"
+	"margo.sh/golang/goutil"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CompositeKind describes the kind of type of a composite literal
+type CompositeKind uint8
+
+const (
+	// UnknownComposite is the zero value, the type of the composite literal couldn't be resolved
+	UnknownComposite CompositeKind = iota
+
+	// StructComposite is a struct literal e.g. `T{‸}` where `type T struct{...}`
+	StructComposite
+
+	// SliceComposite is a slice literal e.g. `[]T{‸}`
+	SliceComposite
+
+	// ArrayComposite is an array literal e.g. `[4]T{‸}` or `[...]T{‸}`
+	ArrayComposite
+
+	// MapComposite is a map literal e.g. `map[K]V{‸}`
+	MapComposite
+)
+
+// CompositeLitKind returns the kind of the composite literal enclosing the cursor.
+// Its type is resolved as in NestedCompositeFieldType, so elided types in nested literals
+// and the types declared in the file are handled.
+func (cx *CurCtx) CompositeLitKind() (kind CompositeKind, ok bool) {
+	typ, ok := cx.NestedCompositeFieldType()
+	if !ok {
+		return UnknownComposite, false
+	}
+	switch x := cx.underlyingType(typ).(type) {
+	case *ast.StructType:
+		return StructComposite, true
+	case *ast.ArrayType:
+		if x.Len == nil {
+			return SliceComposite, true
+		}
+		return ArrayComposite, true
+	case *ast.MapType:
+		return MapComposite, true
+	}
+	return UnknownComposite, false
+}
+
+// CompositeLitTypeName returns the name of the named type of the composite literal enclosing the cursor
+// e.g. `T` in `T{‸}`, `pkg.T` in `pkg.T{‸}` or, for elided types, `T` in `[]T{{‸}}`.
+// isPointer is true if the literal's address is taken e.g. `&T{‸}`, or it's an elided pointer e.g. `[]*T{{‸}}`.
+//
+// Unlike NestedCompositeFieldType, the type doesn't need to be declared in the file, only named.
+// ok is false for anonymous types e.g. `[]int{‸}` or `struct{}{‸}`.
+func (cx *CurCtx) CompositeLitTypeName() (name string, isPointer bool, ok bool) {
+	typ, elidedPtr, ok := cx.nestedCompositeLitType()
+	if !ok {
+		return "", false, false
+	}
+	switch x := typ.(type) {
+	case *ast.SelectorExpr:
+		if id, ok := x.X.(*ast.Ident); ok {
+			name = id.Name + "." + x.Sel.Name
+		}
+	default:
+		if id := typeNameIdent(typ); id != nil {
+			name = id.Name
+		}
+	}
+	if name == "" {
+		return "", false, false
+	}
+	return name, elidedPtr || cx.CompositeLitAddressed(), true
+}
+
+// CompositeLitQualifiedType returns the package name and type name of the composite literal enclosing the cursor
+// iff its type is qualified by one of the file's imports e.g. `http` and `Server` in `&http.Server{‸}`.
+// pkgAlias is the name the package is imported as, which may differ from its package name.
+//
+// ok is false for unqualified or anonymous types, and for qualifiers that aren't imported.
+func (cx *CurCtx) CompositeLitQualifiedType() (pkgAlias, typeName string, ok bool) {
+	typ, _, ok := cx.nestedCompositeLitType()
+	if !ok {
+		return "", "", false
+	}
+	switch x := typ.(type) {
+	case *ast.IndexExpr:
+		typ = x.X
+	case *ast.IndexListExpr:
+		typ = x.X
+	}
+	se, _ := typ.(*ast.SelectorExpr)
+	if se == nil {
+		return "", "", false
+	}
+	id, _ := se.X.(*ast.Ident)
+	if id == nil || !cx.importNames()[id.Name] {
+		return "", "", false
+	}
+	return id.Name, se.Sel.Name, true
+}
+
+// AtCompositeLitStart returns the composite literal whose `{` is immediately before the cursor
+// iff no elements have been typed yet e.g. `T{‸}` or `T{\n\t‸\n}`.
+// Block braces e.g. `func() {‸}` are not composite literals.
+func (cx *CurCtx) AtCompositeLitStart() (*ast.CompositeLit, bool) {
+	lit, _ := cx.enclosingCompositeLit()
+	if lit == nil || len(lit.Elts) != 0 {
+		return nil, false
+	}
+	start := cx.TokenFile.Offset(lit.Lbrace) + 1
+	if cx.srcPos < start || len(bytes.TrimSpace(cx.Src[start:cx.srcPos])) != 0 {
+		return nil, false
+	}
+	return lit, true
+}
+
+// SliceLitElemIndex returns the zero-based index of the element the cursor is on
+// in the slice or array composite literal enclosing the cursor e.g. 2 in `[]T{a, b, ‸}`.
+//
+// As for EnclosingCall, the index is computed from the source, so it's robust to trailing commas and newlines.
+// ok is false if any element is indexed e.g. `[]string{2: "b", ‸}`.
+func (cx *CurCtx) SliceLitElemIndex() (index int, ok bool) {
+	lit, _ := cx.enclosingCompositeLit()
+	if lit == nil {
+		return 0, false
+	}
+	switch kind, ok := cx.CompositeLitKind(); {
+	case kind == SliceComposite || kind == ArrayComposite:
+	case ok:
+		return 0, false
+	default:
+		// the type may not be resolvable e.g. `[]pkg.T{‸}`
+		if _, isArray := lit.Type.(*ast.ArrayType); !isArray {
+			return 0, false
+		}
+	}
+	for _, e := range lit.Elts {
+		if _, ok := e.(*ast.KeyValueExpr); ok {
+			return 0, false
+		}
+	}
+	return callArgIndex(cx.Src[cx.TokenFile.Offset(lit.Lbrace)+1 : cx.srcPos]), true
+}
+
+// CompositeLitElemCount returns the number of elements in the composite literal enclosing the cursor
+// e.g. 2 in `[]T{\n\t{A: 1},\n\t{A: 2},\n\t‸\n}`, for numbering new elements.
+// Key-value pairs count as a single element.
+func (cx *CurCtx) CompositeLitElemCount() (int, bool) {
+	lit, _ := cx.enclosingCompositeLit()
+	if lit == nil {
+		return 0, false
+	}
+	return len(lit.Elts), true
+}
+
+// CompositeLitNeedsTrailingComma returns the offset, in cx.Src, after the last element
+// of the multi-line composite literal enclosing the cursor iff that element isn't followed by a comma
+// e.g. after `b` in `T{\n\ta,\n\tb‸\n}`.
+//
+// Literals whose last element is on the same line as the closing `}` e.g. `T{\n\ta, b}` don't need a trailing comma.
+func (cx *CurCtx) CompositeLitNeedsTrailingComma() (insertPos int, ok bool) {
+	lit, _ := cx.enclosingCompositeLit()
+	if lit == nil || len(lit.Elts) == 0 || !lit.Rbrace.IsValid() {
+		return 0, false
+	}
+	last := lit.Elts[len(lit.Elts)-1]
+	rbraceLine := cx.TokenFile.Line(lit.Rbrace)
+	if cx.TokenFile.Line(lit.Lbrace) == rbraceLine || cx.TokenFile.Line(last.End()) == rbraceLine {
+		return 0, false
+	}
+	insertPos = cx.TokenFile.Offset(last.End())
+	src := cx.Src[insertPos:cx.TokenFile.Offset(lit.Rbrace)]
+	var sc scanner.Scanner
+	sc.Init(token.NewFileSet().AddFile("", -1, len(src)), src, nil, 0)
+	for {
+		_, tok, lit := sc.Scan()
+		switch {
+		case tok == token.COMMA:
+			return 0, false
+		case tok == token.SEMICOLON && lit == "\n":
+			// automatically inserted at newlines
+		default:
+			return insertPos, true
+		}
+	}
+}
+
+// CompositeTypeHeaderContext returns the kind of the composite literal type whose element type the cursor is in
+// e.g. `[]‸{}`, `[3]*‸{}` or `map[string]‸{}`, so type names should be offered.
+// The braces are optional, but the literal body, e.g. `[]T{‸}`, and the length of arrays, e.g. `[‸]T{}`, are not type positions.
+//
+// Types that are not in expressions e.g. `var x []‸` are not composite literal types.
+func (cx *CurCtx) CompositeTypeHeaderContext() (kind CompositeKind, ok bool) {
+	// find the outermost array or map type of the type expression enclosing the cursor
+	top := -1
+Loop:
+	for i := len(cx.Nodes) - 1; i >= 0; i-- {
+		switch cx.Nodes[i].(type) {
+		case *ast.ArrayType, *ast.MapType:
+			top = i
+		case *ast.Ident, *ast.SelectorExpr, *ast.StarExpr, *ast.ChanType,
+			*ast.IndexExpr, *ast.IndexListExpr, *ast.BadExpr:
+		default:
+			break Loop
+		}
+	}
+	if top < 1 {
+		return UnknownComposite, false
+	}
+
+	typ := cx.Nodes[top].(ast.Expr)
+	switch x := cx.Nodes[top-1].(type) {
+	case *ast.CompositeLit:
+		if x.Type != typ || cx.TokenPos > x.Lbrace {
+			return UnknownComposite, false
+		}
+	case *ast.ValueSpec:
+		if x.Type == typ {
+			return UnknownComposite, false
+		}
+	case *ast.Field, *ast.TypeSpec:
+		return UnknownComposite, false
+	}
+
+	var rbrack token.Pos
+	switch x := typ.(type) {
+	case *ast.ArrayType:
+		kind, rbrack = SliceComposite, x.Lbrack+1
+		if x.Len != nil {
+			kind, rbrack = ArrayComposite, x.Len.End()
+		}
+	case *ast.MapType:
+		// the key type is a type position too
+		kind, rbrack = MapComposite, x.Map+token.Pos(len("map"))
+	}
+	if cx.srcPos <= cx.TokenFile.Offset(rbrack) {
+		return UnknownComposite, false
+	}
+	return kind, true
+}
+
+// CompositeLitAddressed returns true if the composite literal enclosing the cursor
+// is the operand of a unary `&` e.g. `&T{‸}`, or the `&` is elided e.g. `[]*T{{‸}}`
+func (cx *CurCtx) CompositeLitAddressed() bool {
+	lit, i := cx.enclosingCompositeLit()
+	if i < 1 {
+		return false
+	}
+	if x, ok := cx.Nodes[i-1].(*ast.UnaryExpr); ok && x.Op == token.AND {
+		return true
+	}
+	if lit.Type != nil {
+		return false
+	}
+	_, elidedPtr, _ := cx.nestedCompositeLitType()
+	return elidedPtr
+}
+
+// enclosingCompositeLit returns the innermost composite literal whose braces enclose the cursor
+// and its index in cx.Nodes.
+// It returns (nil, -1) if a func literal or statement is reached first.
+func (cx *CurCtx) enclosingCompositeLit() (*ast.CompositeLit, int) {
+	for i := len(cx.Nodes) - 1; i >= 0; i-- {
+		switch x := cx.Nodes[i].(type) {
+		case *ast.CompositeLit:
+			if cx.inBrackets(x.Lbrace, x.Rbrace) {
+				return x, i
+			}
+		case *ast.FuncLit, ast.Stmt:
+			return nil, -1
+		}
+	}
+	return nil, -1
+}
+
+// CompositeLitStack returns the directly nested composite literals enclosing the cursor, outermost first
+// e.g. `Server{...}` and `TLSConfig{...}` in `Server{TLS: TLSConfig{‸}}`.
+//
+// Literals are directly nested if the inner literal is an element, key or value of the outer literal,
+// optionally with its address taken e.g. `[]*T{&T{‸}}`.
+func (cx *CurCtx) CompositeLitStack() []*ast.CompositeLit {
+	lit, i := cx.enclosingCompositeLit()
+	if lit == nil {
+		return nil
+	}
+	stack := []*ast.CompositeLit{lit}
+	for i--; i >= 0; i-- {
+		switch x := cx.Nodes[i].(type) {
+		case *ast.KeyValueExpr:
+			continue
+		case *ast.UnaryExpr:
+			if x.Op == token.AND {
+				continue
+			}
+		case *ast.CompositeLit:
+			stack = append(stack, x)
+			continue
+		}
+		break
+	}
+	for i, j := 0, len(stack)-1; i < j; i, j = i+1, j-1 {
+		stack[i], stack[j] = stack[j], stack[i]
+	}
+	return stack
+}
+
+// CompositeFieldPath returns the path of field keys and indices from the outermost composite literal
+// in CompositeLitStack to the cursor e.g. `[Server TLS Cert]` in `Server{TLS: TLSConfig{Cert: ‸}}`
+// or `[Config Servers[0] Name]` in `Config{Servers: []Server{{Name: ‸}}}`.
+// Join it with "." to display it e.g. `Server.TLS.Cert`.
+//
+// The path starts with the name of the outermost literal's type if it's a named type.
+// Indices are appended to the preceding element, slice and array elements are numbered from 0 and map keys are printed as written.
+func (cx *CurCtx) CompositeFieldPath() ([]string, bool) {
+	stack := cx.CompositeLitStack()
+	if len(stack) == 0 {
+		return nil, false
+	}
+	path := []string{}
+	add := func(s string) {
+		if strings.HasPrefix(s, "[") && len(path) != 0 {
+			path[len(path)-1] += s
+		} else {
+			path = append(path, s)
+		}
+	}
+	switch x := stack[0].Type.(type) {
+	case *ast.SelectorExpr:
+		s, _ := cx.Print(x)
+		add(s)
+	default:
+		if id := typeNameIdent(x); id != nil {
+			add(id.Name)
+		}
+	}
+	var typ ast.Expr
+	for i, lit := range stack {
+		switch {
+		case lit.Type != nil:
+			typ = lit.Type
+		case typ != nil:
+			typ, _ = cx.compositeLitEltType(typ, stack[i-1], lit)
+		}
+		u := cx.underlyingType(typ)
+		_, isStruct := u.(*ast.StructType)
+		_, isArray := u.(*ast.ArrayType)
+		_, isMap := u.(*ast.MapType)
+		pos := cx.TokenPos
+		if i+1 < len(stack) {
+			pos = stack[i+1].Pos()
+		}
+		index := -1
+		for j, e := range lit.Elts {
+			if goutil.NodeEnclosesPos(e, pos) {
+				index = j
+				break
+			}
+		}
+		if index < 0 {
+			if i+1 < len(stack) {
+				return nil, false
+			}
+			// the cursor is between elements e.g. `[]T{a, ‸}`
+			if index, ok := cx.SliceLitElemIndex(); ok && isArray {
+				add(fmt.Sprintf("[%d]", index))
+			}
+			break
+		}
+		kv, _ := lit.Elts[index].(*ast.KeyValueExpr)
+		switch {
+		case kv == nil && isStruct:
+			// positional struct fields have no name
+			return nil, false
+		case kv == nil:
+			add(fmt.Sprintf("[%d]", index))
+		case pos <= kv.Colon:
+			// the cursor is in the key
+			if i+1 < len(stack) {
+				return nil, false
+			}
+		default:
+			key, _ := cx.Print(kv.Key)
+			if _, isIdent := kv.Key.(*ast.Ident); isIdent && !isArray && !isMap {
+				add(key)
+			} else {
+				add("[" + key + "]")
+			}
+		}
+	}
+	return path, true
+}
+
+// CompositeLitDepth returns the number of directly nested composite literals enclosing the cursor,
+// as returned by CompositeLitStack, e.g. 2 in `Server{TLS: TLSConfig{‸}}`, or 0 if there are none.
+func (cx *CurCtx) CompositeLitDepth() int {
+	return len(cx.CompositeLitStack())
+}
+
+// NestedCompositeFieldType returns the type of the innermost composite literal enclosing the cursor
+// e.g. `TLSConfig` in `Server{TLS: TLSConfig{‸}}`.
+//
+// Elided types are resolved by walking CompositeLitStack, using the field keys and the types declared in the file
+// e.g. `Cert` in `Server{Certs: []Cert{{‸}}}` or `struct{ Addr string }` in `Server{Listen: struct{ Addr string }{‸}}`.
+// ok is false if a type can't be resolved, e.g. because it's declared in another package.
+func (cx *CurCtx) NestedCompositeFieldType() (typ ast.Expr, ok bool) {
+	typ, _, ok = cx.nestedCompositeLitType()
+	return typ, ok
+}
+
+// InheritedCompositeType returns the type of the innermost composite literal enclosing the cursor
+// iff its type is elided and inherited from the enclosing literal e.g. `Cert` in `Server{Certs: []*Cert{{‸}}}`.
+//
+// The type is resolved as for NestedCompositeFieldType, applying the rules at each level of CompositeLitStack:
+// slice and array literals give their element type, map literals their key or value type,
+// and struct literals the type of the field, by key or position. Elided pointers are dereferenced.
+func (cx *CurCtx) InheritedCompositeType() (ast.Expr, bool) {
+	stack := cx.CompositeLitStack()
+	if len(stack) < 2 || stack[len(stack)-1].Type != nil {
+		return nil, false
+	}
+	return cx.NestedCompositeFieldType()
+}
+
+// nestedCompositeLitType implements NestedCompositeFieldType.
+// elidedPtr is true if the type of the innermost literal is an elided pointer e.g. `[]*T{{‸}}`.
+func (cx *CurCtx) nestedCompositeLitType() (typ ast.Expr, elidedPtr bool, ok bool) {
+	stack := cx.CompositeLitStack()
+	for i, lit := range stack {
+		switch {
+		case lit.Type != nil:
+			typ, elidedPtr = lit.Type, false
+		case i == 0:
+			return nil, false, false
+		default:
+			typ, elidedPtr = cx.compositeLitEltType(typ, stack[i-1], lit)
+		}
+		if typ == nil {
+			return nil, false, false
+		}
+	}
+	return typ, elidedPtr, typ != nil
+}
+
+// CompositeLitTypeFromContext returns the type of the composite literal enclosing the cursor
+// as NestedCompositeFieldType does, or if the literal's type is elided where it's not allowed,
+// the type expected by its context e.g. `Item` in `append(items, {‸})` where `var items []Item`.
+//
+// The parser discards such literals, so only the outermost literal, whose `{` is the start of the
+// expression, is supported. The contexts supported are the values of `append` calls, var specs with a type,
+// assignments to variables, and return statements.
+func (cx *CurCtx) CompositeLitTypeFromContext() (ast.Expr, bool) {
+	if typ, ok := cx.NestedCompositeFieldType(); ok {
+		return typ, true
+	}
+	if len(cx.CompositeLitStack()) != 0 {
+		return nil, false
+	}
+	typ := cx.elidedCompositeLitType()
+	return typ, typ != nil
+}
+
+// CompositeLitTypeText returns the type returned by CompositeLitTypeFromContext as text on a single line,
+// for display e.g. `pkg.Config` in `pkg.Config{‸}` or `struct { Addr string; Port int }` for a multi-line anonymous struct type.
+func (cx *CurCtx) CompositeLitTypeText() (string, bool) {
+	typ, ok := cx.CompositeLitTypeFromContext()
+	if !ok {
+		return "", false
+	}
+	// RawFormat leaves the alignment of fields as tabs, instead of padding, which cx.Print would remove
+	src := &bytes.Buffer{}
+	if err := (&printer.Config{Mode: printer.RawFormat}).Fprint(src, token.NewFileSet(), typ); err != nil {
+		return "", false
+	}
+	s := src.String()
+	if !strings.Contains(s, "\n") {
+		return s, true
+	}
+	buf := &strings.Builder{}
+	for i, ln := range strings.Split(s, "\n") {
+		ln = strings.Join(strings.Fields(ln), " ")
+		switch {
+		case ln == "":
+			continue
+		case i == 0:
+		case strings.HasSuffix(buf.String(), "{"), strings.HasPrefix(ln, "}"):
+			buf.WriteByte(' ')
+		default:
+			buf.WriteString("; ")
+		}
+		buf.WriteString(ln)
+	}
+	return buf.String(), true
+}
+
+// CompositeLitInReturn returns the result type of the enclosing function corresponding to the composite literal
+// returned at the cursor e.g. `Response` in `return nil, Response{‸}` or `return nil, &{‸}`
+// where the function is declared as `func f() (error, *Response)`.
+//
+// The literal's own type is ignored, so it may be elided. Pointer result types are dereferenced.
+func (cx *CurCtx) CompositeLitInReturn() (resultType ast.Expr, ok bool) {
+	ret, fn := cx.enclosingReturn()
+	if ret == nil {
+		return nil, false
+	}
+	i, _ := cx.ReturnExprIndex()
+	ft, _ := funcTypeBody(fn)
+	results := fieldListParams(ft.Results)
+	if i >= len(results) || i >= len(ret.Results) {
+		return nil, false
+	}
+
+	x := ret.Results[i]
+	if u, ok := x.(*ast.UnaryExpr); ok && u.Op == token.AND {
+		x = u.X
+	}
+	switch x := x.(type) {
+	case *ast.CompositeLit:
+		if stack := cx.CompositeLitStack(); len(stack) == 0 || stack[0] != x {
+			return nil, false
+		}
+	case *ast.BadExpr:
+		// the parser discards type-elided literals
+		if p := cx.TokenFile.Offset(x.Pos()); p >= cx.srcPos || cx.Src[p] != '{' {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+
+	resultType = results[i].Type
+	if x, ok := resultType.(*ast.StarExpr); ok {
+		resultType = x.X
+	}
+	return resultType, true
+}
+
+// elidedCompositeLitType returns the type expected by the context of the expression
+// in cx.Node that starts with `{` before the cursor, and which the parser discarded.
+func (cx *CurCtx) elidedCompositeLitType() ast.Expr {
+	exprIndex := func(l []ast.Expr) int {
+		for i, x := range l {
+			if _, ok := x.(*ast.BadExpr); !ok {
+				continue
+			}
+			if p := cx.TokenFile.Offset(x.Pos()); p < cx.srcPos && cx.Src[p] == '{' {
+				return i
+			}
+		}
+		return -1
+	}
+
+	var typ ast.Expr
+	switch x := cx.Node.(type) {
+	case *ast.CallExpr:
+		fun, _ := x.Fun.(*ast.Ident)
+		if fun == nil || fun.Name != "append" || exprIndex(x.Args) < 1 || cx.isDeclared("append") {
+			return nil
+		}
+		if at, ok := cx.underlyingType(cx.valueType(x.Args[0])).(*ast.ArrayType); ok {
+			typ = at.Elt
+		}
+	case *ast.ValueSpec:
+		if exprIndex(x.Values) >= 0 {
+			typ = x.Type
+		}
+	case *ast.AssignStmt:
+		if j := exprIndex(x.Rhs); x.Tok == token.ASSIGN && j >= 0 && len(x.Lhs) == len(x.Rhs) {
+			typ = cx.valueType(x.Lhs[j])
+		}
+	case *ast.ReturnStmt:
+		ret, fn := cx.enclosingReturn()
+		ft, _ := funcTypeBody(fn)
+		if j := exprIndex(x.Results); ret == x && ft != nil && j >= 0 {
+			if results := fieldListParams(ft.Results); len(results) == len(x.Results) {
+				typ = results[j].Type
+			}
+		}
+	}
+	if x, ok := typ.(*ast.StarExpr); ok {
+		typ = x.X
+	}
+	return typ
+}
+
+// valueType returns the syntactic type of the variable or trivially typed expression x
+func (cx *CurCtx) valueType(x ast.Expr) ast.Expr {
+	if id, ok := x.(*ast.Ident); ok {
+		typ, _ := cx.varType(id.Name)
+		return typ
+	}
+	return exprType(x)
+}
+
+// StructField describes a single field of a struct type
+type StructField struct {
+	// Name is the name of the field, or the type name for embedded fields
+	Name string
+
+	// Type is the declared type of the field
+	Type ast.Expr
+
+	// Embedded is true if the field is embedded e.g. `io.Reader` in `struct{ io.Reader }`
+	Embedded bool
+}
+
+// LocalStructFields returns the fields, in declaration order, of the struct type of the composite literal
+// enclosing the cursor, as resolved by NestedCompositeFieldType.
+// ok is false if the type is not a struct declared in the file.
+func (cx *CurCtx) LocalStructFields() (fields []StructField, ok bool) {
+	typ, ok := cx.NestedCompositeFieldType()
+	if !ok {
+		return nil, false
+	}
+	st, _ := cx.underlyingType(typ).(*ast.StructType)
+	if st == nil || st.Fields == nil {
+		return nil, false
+	}
+	return structFields(st), true
+}
+
+// structFields returns the fields, in declaration order, of the struct type st
+func structFields(st *ast.StructType) []StructField {
+	fields := []StructField{}
+	if st.Fields == nil {
+		return fields
+	}
+	for _, f := range st.Fields.List {
+		if len(f.Names) != 0 {
+			for _, id := range f.Names {
+				fields = append(fields, StructField{Name: id.Name, Type: f.Type})
+			}
+			continue
+		}
+		typ := f.Type
+		if x, ok := typ.(*ast.StarExpr); ok {
+			typ = x.X
+		}
+		if x, ok := typ.(*ast.SelectorExpr); ok {
+			typ = x.Sel
+		}
+		if id := typeNameIdent(typ); id != nil {
+			fields = append(fields, StructField{Name: id.Name, Type: f.Type, Embedded: true})
+		}
+	}
+	return fields
+}
+
+// InTestCaseTable returns the fields of the anonymous struct type of a table of test cases
+// e.g. `name` and `want` in `tests := []struct{ name, want string }{ ‸ }`.
+// ok is true if the cursor is in the slice literal, or one of its elements, and the file is a test file.
+func (cx *CurCtx) InTestCaseTable() (fields []StructField, ok bool) {
+	if !cx.IsTestFile {
+		return nil, false
+	}
+	stack := cx.CompositeLitStack()
+	if len(stack) == 0 {
+		return nil, false
+	}
+	lit := stack[len(stack)-1]
+	if lit.Type == nil && len(stack) >= 2 {
+		// the cursor is in an element e.g. `{name: ‸}`
+		lit = stack[len(stack)-2]
+	}
+	at, _ := lit.Type.(*ast.ArrayType)
+	if at == nil {
+		return nil, false
+	}
+	st, _ := at.Elt.(*ast.StructType)
+	if st == nil {
+		return nil, false
+	}
+	return structFields(st), true
+}
+
+// CompositeLitKeys returns the identifier keys present in the composite literal enclosing the cursor
+// e.g. `[A, B]` in `T{A: 1, B: 2, ‸}`.
+// The key being typed at the cursor is not included.
+func (cx *CurCtx) CompositeLitKeys() []string {
+	lit, _ := cx.enclosingCompositeLit()
+	if lit == nil {
+		return nil
+	}
+	keys := []string{}
+	for _, e := range lit.Elts {
+		kv, _ := e.(*ast.KeyValueExpr)
+		if kv == nil {
+			continue
+		}
+		if id, ok := kv.Key.(*ast.Ident); ok && !goutil.NodeEnclosesPos(id, cx.TokenPos) {
+			keys = append(keys, id.Name)
+		}
+	}
+	return keys
+}
+
+// CompositeKeyExists returns true if key is already a key in the keyed composite literal enclosing the cursor
+// e.g. `Name` in `T{Name: "a", ‸}` or `"a"` in `map[string]int{"a": 1, ‸}`.
+// The key being typed at the cursor is not considered.
+//
+// Keys are compared syntactically, so only field names and constant keys e.g. `"a"`, `1` or `pkg.C` are supported;
+// string keys are compared by value, so interpreted and raw strings with the same value are the same key.
+func (cx *CurCtx) CompositeKeyExists(key string) bool {
+	lit, _ := cx.enclosingCompositeLit()
+	if lit == nil {
+		return false
+	}
+	kx, err := parser.ParseExpr(key)
+	if err != nil {
+		return false
+	}
+	want, ok := compositeKeyString(kx)
+	if !ok {
+		return false
+	}
+	for _, e := range lit.Elts {
+		kv, _ := e.(*ast.KeyValueExpr)
+		if kv == nil || goutil.NodeEnclosesPos(kv.Key, cx.TokenPos) {
+			continue
+		}
+		if got, ok := compositeKeyString(kv.Key); ok && got == want {
+			return true
+		}
+	}
+	return false
+}
+
+// compositeKeyString returns a normalised representation of the composite literal key x,
+// if it's a field name or constant
+func compositeKeyString(x ast.Expr) (string, bool) {
+	switch x := x.(type) {
+	case *ast.Ident:
+		return x.Name, true
+	case *ast.BasicLit:
+		if x.Kind != token.STRING {
+			return x.Value, true
+		}
+		s, err := strconv.Unquote(x.Value)
+		return strconv.Quote(s), err == nil
+	case *ast.SelectorExpr:
+		if id, ok := x.X.(*ast.Ident); ok {
+			return id.Name + "." + x.Sel.Name, true
+		}
+	case *ast.ParenExpr:
+		return compositeKeyString(x.X)
+	}
+	return "", false
+}
+
+// RemainingStructFields returns the fields of LocalStructFields whose keys are not in CompositeLitKeys,
+// in declaration order.
+// ok is false if the composite literal is positional e.g. `T{1, ‸}`, or its type is not a struct declared in the file.
+func (cx *CurCtx) RemainingStructFields() (fields []StructField, ok bool) {
+	if !cx.compositeLitKeyed() {
+		return nil, false
+	}
+	all, ok := cx.LocalStructFields()
+	if !ok {
+		return nil, false
+	}
+	present := map[string]bool{}
+	for _, k := range cx.CompositeLitKeys() {
+		present[k] = true
+	}
+	fields = []StructField{}
+	for _, f := range all {
+		if !present[f.Name] {
+			fields = append(fields, f)
+		}
+	}
+	return fields, true
+}
+
+// StructFieldRanker ranks the struct field f for SuggestedStructFields. Fields with lower ranks are suggested first.
+type StructFieldRanker func(f StructField) int
+
+var (
+	structFieldRanker = struct {
+		sync.RWMutex
+		f StructFieldRanker
+	}{f: DefaultStructFieldRank}
+)
+
+// SetStructFieldRanker replaces the StructFieldRanker used by SuggestedStructFields.
+// If f is nil, DefaultStructFieldRank is restored.
+func SetStructFieldRanker(f StructFieldRanker) {
+	structFieldRanker.Lock()
+	defer structFieldRanker.Unlock()
+
+	if f == nil {
+		f = DefaultStructFieldRank
+	}
+	structFieldRanker.f = f
+}
+
+// DefaultStructFieldRank is the default StructFieldRanker.
+// It guesses that fields are more likely to need setting if they're exported,
+// not pointers (which are often optional) and not bools (whose zero value is often the desired default).
+// Exportedness outweighs the other two.
+func DefaultStructFieldRank(f StructField) int {
+	rank := 0
+	if !ast.IsExported(f.Name) {
+		rank += 4
+	}
+	if _, ok := f.Type.(*ast.StarExpr); ok {
+		rank += 2
+	}
+	if id, ok := f.Type.(*ast.Ident); ok && id.Name == "bool" {
+		rank++
+	}
+	return rank
+}
+
+// SuggestedStructFields returns the fields of RemainingStructFields ordered by the StructFieldRanker
+// set by SetStructFieldRanker, as a heuristic for the fields that most likely need setting.
+// Fields of equal rank are kept in declaration order.
+func (cx *CurCtx) SuggestedStructFields() ([]StructField, bool) {
+	fields, ok := cx.RemainingStructFields()
+	if !ok {
+		return nil, false
+	}
+	structFieldRanker.RLock()
+	rank := structFieldRanker.f
+	structFieldRanker.RUnlock()
+
+	ranks := make(map[string]int, len(fields))
+	for _, f := range fields {
+		ranks[f.Name] = rank(f)
+	}
+	sort.SliceStable(fields, func(i, j int) bool {
+		return ranks[fields[i].Name] < ranks[fields[j].Name]
+	})
+	return fields, true
+}
+
+// FieldTypeForKey returns the type of the field key of the struct type of the composite literal enclosing the cursor
+// e.g. `bool` for key `Debug` in `Config{Debug: ‸}` where `type Config struct{ Debug bool }`.
+// ok is false if the composite literal is positional, its type is not a struct declared in the file,
+// or it has no field key.
+func (cx *CurCtx) FieldTypeForKey(key string) (ast.Expr, bool) {
+	if !cx.compositeLitKeyed() {
+		return nil, false
+	}
+	fields, _ := cx.LocalStructFields()
+	for _, f := range fields {
+		if f.Name == key {
+			return f.Type, true
+		}
+	}
+	return nil, false
+}
+
+// MapLitKeyType returns the key type of the map composite literal enclosing the cursor
+// iff the cursor is in a key position e.g. `string` in `map[string]int{‸}` or `map[string]int{"a": 1, ‸}`.
+//
+// Elided types are resolved as for NestedCompositeFieldType e.g. `Key` in `map[string]map[Key]int{"a": {‸}}`.
+func (cx *CurCtx) MapLitKeyType() (ast.Expr, bool) {
+	lit, _ := cx.enclosingCompositeLit()
+	if lit == nil {
+		return nil, false
+	}
+	for _, e := range lit.Elts {
+		kv, ok := e.(*ast.KeyValueExpr)
+		if ok && cx.TokenFile.Offset(kv.Colon) < cx.srcPos && cx.srcPos <= cx.TokenFile.Offset(kv.End()) {
+			return nil, false
+		}
+	}
+	typ, ok := cx.NestedCompositeFieldType()
+	if !ok {
+		return nil, false
+	}
+	mt, _ := cx.underlyingType(typ).(*ast.MapType)
+	if mt == nil {
+		return nil, false
+	}
+	return mt.Key, true
+}
+
+// compositeLitKeyed returns true if the composite literal enclosing the cursor is not positional
+// i.e. all its elements are key-value pairs, except the element being typed at the cursor
+func (cx *CurCtx) compositeLitKeyed() bool {
+	lit, _ := cx.enclosingCompositeLit()
+	if lit == nil {
+		return false
+	}
+	for _, e := range lit.Elts {
+		// the element at the cursor may be a key that's still being typed
+		if _, isKV := e.(*ast.KeyValueExpr); !isKV && !goutil.NodeEnclosesPos(e, cx.TokenPos) {
+			return false
+		}
+	}
+	return true
+}
+
+// compositeLitEltType returns the type of lit, which is nested in the composite literal parent of type typ.
+// elidedPtr is true if the element type is a pointer, whose `&` was elided.
+func (cx *CurCtx) compositeLitEltType(typ ast.Expr, parent, lit *ast.CompositeLit) (elt ast.Expr, elidedPtr bool) {
+	var kv *ast.KeyValueExpr
+	for _, e := range parent.Elts {
+		if x, ok := e.(*ast.KeyValueExpr); ok && goutil.NodeEnclosesPos(x, lit.Pos()) {
+			kv = x
+			break
+		}
+	}
+
+	switch u := cx.underlyingType(typ).(type) {
+	case *ast.StructType:
+		if kv == nil {
+			// positional elements e.g. `T{1, {‸}}` are in field order
+			fields := structFields(u)
+			for i, e := range parent.Elts {
+				if i < len(fields) && goutil.NodeEnclosesPos(e, lit.Pos()) {
+					elt = fields[i].Type
+				}
+			}
+			break
+		}
+		key, _ := kv.Key.(*ast.Ident)
+		if key == nil {
+			return nil, false
+		}
+		elt = structFieldType(u, key.Name)
+	case *ast.ArrayType:
+		elt = u.Elt
+	case *ast.MapType:
+		elt = u.Value
+		if kv != nil && goutil.NodeEnclosesPos(kv.Key, lit.Pos()) {
+			elt = u.Key
+		}
+	}
+	// `[]*T{{‸}}` is short for `[]*T{&T{‸}}`
+	if x, ok := elt.(*ast.StarExpr); ok {
+		return x.X, true
+	}
+	return elt, false
+}
+
+// underlyingType resolves typ through the type names declared in the file
+// e.g. `struct{...}` for `T` where `type T struct{...}`.
+// It returns nil if typ is declared in another package.
+func (cx *CurCtx) underlyingType(typ ast.Expr) ast.Expr {
+	// limit the depth to guard against cycles like `type A B; type B A`
+	for i := 0; i < 10; i++ {
+		switch x := typ.(type) {
+		case *ast.ParenExpr:
+			typ = x.X
+		case *ast.IndexExpr:
+			typ = x.X
+		case *ast.IndexListExpr:
+			typ = x.X
+		case *ast.Ident:
+			t, ok := cx.typeDecl(x.Name)
+			if !ok {
+				return nil
+			}
+			typ = t
+		default:
+			return typ
+		}
+	}
+	return nil
+}
+
+// structFieldType returns the type of the field name in st, including embedded fields
+func structFieldType(st *ast.StructType, name string) ast.Expr {
+	if st.Fields == nil {
+		return nil
+	}
+	for _, f := range st.Fields.List {
+		if len(f.Names) == 0 {
+			typ := f.Type
+			if x, ok := typ.(*ast.StarExpr); ok {
+				typ = x.X
+			}
+			if x, ok := typ.(*ast.SelectorExpr); ok {
+				typ = x.Sel
+			}
+			if id := typeNameIdent(typ); id != nil && id.Name == name {
+				return f.Type
+			}
+			continue
+		}
+		for _, id := range f.Names {
+			if id.Name == name {
+				return f.Type
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,673 @@
+package cursor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNestedCompositeFieldType(t *testing.T) {
+	decls := "package p\ntype Server struct {\n\tTLS   TLSConfig\n\tCerts []*Cert\n\tByName map[string]Cert\n\tListen struct{ Addr string }\n\tOpts  []Opt\n\t*Cert\n}\ntype Cert struct{ File string }\ntype TLSConfig struct{ Min int }\ntype Servers []Server\n"
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"var _ = Server{TLS: TLSConfig{‸}}", "TLSConfig"},
+		{"var _ = Server{Certs: []*Cert{{‸}}}", "Cert"},
+		{"var _ = Server{Certs: []*Cert{&Cert{‸}}}", "Cert"},
+		{"var _ = Server{ByName: map[string]Cert{\"a\": {‸}}}", "Cert"},
+		{"var _ = Server{Cert: &Cert{‸}}", "Cert"},
+		{"var _ = Servers{{TLS: TLSConfig{‸}}}", "TLSConfig"},
+		{"var _ = Servers{{‸}}", "Server"},
+		{"var _ = []Server{{Certs: {{‸}}}}", "Cert"},
+		{"var _ = Server{‸}", "Server"},
+		{"var _ = Server{Opts: []Opt{{‸}}}", "Opt"},
+		{"var _ = Server{Listen: {‸}}", "struct{ Addr string }"},
+		{"var _ = []tls.Config{{‸}}", "tls.Config"},
+		{"var _ = tls.Configs{{‸}}", ""},
+		{"var _ = Server{TLS: f(TLSConfig{‸})}", "TLSConfig"},
+		{"var _ = ‸Server{}", ""},
+	}
+	for _, c := range cases {
+		src := decls + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		typ, ok := cx.NestedCompositeFieldType()
+		got := ""
+		if typ != nil {
+			got, _ = cx.Print(typ)
+		}
+		if got != c.want || ok != (c.want != "") {
+			t.Errorf("NestedCompositeFieldType() = (`%s`, %v), want (`%s`, %v) in %q", got, ok, c.want, c.want != "", c.src)
+		}
+	}
+}
+
+func TestRemainingStructFields(t *testing.T) {
+	decls := "package p\ntype T struct {\n\tA, B int\n\tC    string\n\t*Inner\n}\ntype Inner struct{ X int }\n"
+	cases := []struct {
+		src  string
+		want string
+		ok   bool
+	}{
+		{"var _ = T{‸}", "A, B, C, Inner", true},
+		{"var _ = T{B: 1, ‸}", "A, C, Inner", true},
+		{"var _ = T{A: 1, C: \"\", ‸}", "B, Inner", true},
+		{"var _ = T{A: 1, B‸}", "B, C, Inner", true},
+		{"var _ = T{‸", "A, B, C, Inner", true},
+		{"var _ = T{B: 1,‸", "A, C, Inner", true},
+		{"var _ = []T{{C: \"\", ‸}}", "A, B, Inner", true},
+		{"var _ = T{Inner: &Inner{‸}}", "X", true},
+		{"var _ = T{1, ‸}", "", false},
+		{"var _ = tls.Config{‸}", "", false},
+		{"var _ = []int{‸}", "", false},
+	}
+	for _, c := range cases {
+		src := decls + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		fields, ok := cx.RemainingStructFields()
+		l := []string{}
+		for _, f := range fields {
+			l = append(l, f.Name)
+		}
+		if got := strings.Join(l, ", "); got != c.want || ok != c.ok {
+			t.Errorf("RemainingStructFields() = (`%s`, %v), want (`%s`, %v) in %q", got, ok, c.want, c.ok, c.src)
+		}
+	}
+}
+
+func TestSuggestedStructFields(t *testing.T) {
+	decls := "package p\ntype Server struct {\n\tdebug bool\n\tTLS *TLSConfig\n\tVerbose bool\n\tAddr string\n\tname string\n\tHandler Handler\n}\n"
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"var _ = Server{‸}", "Addr Handler Verbose TLS name debug"},
+		{"var _ = Server{Addr: \":80\", ‸}", "Handler Verbose TLS name debug"},
+		{"var _ = Server{false, ‸}", "-"},
+	}
+	for _, c := range cases {
+		src := decls + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		fields, ok := cx.SuggestedStructFields()
+		got := "-"
+		if ok {
+			l := []string{}
+			for _, f := range fields {
+				l = append(l, f.Name)
+			}
+			got = strings.Join(l, " ")
+		}
+		if got != c.want {
+			t.Errorf("SuggestedStructFields() = %q, want %q in %q", got, c.want, c.src)
+		}
+	}
+
+	SetStructFieldRanker(func(f StructField) int { return -len(f.Name) })
+	defer SetStructFieldRanker(nil)
+	cx := newTestCurCtx(t, decls+"var _ = Server{‸}\n")
+	fields, _ := cx.SuggestedStructFields()
+	if len(fields) == 0 || fields[0].Name != "Verbose" {
+		t.Errorf("SuggestedStructFields() with a custom ranker = %v, want Verbose first", fields)
+	}
+}
+
+func TestCompositeLitKind(t *testing.T) {
+	decls := "package p\ntype T struct{ L []int; M map[string]T }\ntype Ts []T\n"
+	cases := []struct {
+		src  string
+		kind CompositeKind
+	}{
+		{"var _ = T{‸}", StructComposite},
+		{"var _ = []T{‸}", SliceComposite},
+		{"var _ = Ts{‸}", SliceComposite},
+		{"var _ = Ts{{‸}}", StructComposite},
+		{"var _ = [4]int{‸}", ArrayComposite},
+		{"var _ = [...]int{‸}", ArrayComposite},
+		{"var _ = map[string]T{‸}", MapComposite},
+		{"var _ = map[string]T{\"a\": {‸}}", StructComposite},
+		{"var _ = T{L: {‸}}", SliceComposite},
+		{"var _ = T{M: {‸}}", MapComposite},
+		{"var _ = struct{ N int }{‸}", StructComposite},
+		{"var _ = tls.Config{‸}", UnknownComposite},
+		{"var _ = ‸1", UnknownComposite},
+	}
+	for _, c := range cases {
+		src := decls + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		kind, ok := cx.CompositeLitKind()
+		if kind != c.kind || ok != (c.kind != UnknownComposite) {
+			t.Errorf("CompositeLitKind() = (%v, %v), want (%v, %v) in %q", kind, ok, c.kind, c.kind != UnknownComposite, c.src)
+		}
+	}
+}
+
+func TestCompositeLitTypeName(t *testing.T) {
+	decls := "package p\ntype T struct{ C *Cert; Cs []*Cert }\ntype Cert struct{}\n"
+	cases := []struct {
+		src       string
+		name      string
+		isPointer bool
+	}{
+		{"var _ = T{‸}", "T", false},
+		{"var _ = &T{‸}", "T", true},
+		{"var _ = tls.Config{‸}", "tls.Config", false},
+		{"var _ = &tls.Config{‸}", "tls.Config", true},
+		{"var _ = G[int]{‸}", "G", false},
+		{"var _ = []T{{‸}}", "T", false},
+		{"var _ = []*T{{‸}}", "T", true},
+		{"var _ = T{Cs: {{‸}}}", "Cert", true},
+		{"var _ = T{C: &Cert{‸}}", "Cert", true},
+		{"var _ = []tls.Config{{‸}}", "tls.Config", false},
+		{"var _ = []int{‸}", "", false},
+		{"var _ = struct{}{‸}", "", false},
+		{"var _ = ‸1", "", false},
+	}
+	for _, c := range cases {
+		src := decls + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		name, isPointer, ok := cx.CompositeLitTypeName()
+		if name != c.name || isPointer != c.isPointer || ok != (c.name != "") {
+			t.Errorf("CompositeLitTypeName() = (%q, %v, %v), want (%q, %v, %v) in %q", name, isPointer, ok, c.name, c.isPointer, c.name != "", c.src)
+		}
+	}
+}
+
+func TestCompositeLitQualifiedType(t *testing.T) {
+	decls := "package p\nimport (\n\t\"net/http\"\n\tyaml \"gopkg.in/yaml.v3\"\n\t\"example.com/list\"\n)\ntype T struct{}\n"
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"var _ = http.Server{‸}", "http.Server"},
+		{"var _ = &http.Server{Addr: ‸}", "http.Server"},
+		{"var _ = []http.Header{{‸}}", "http.Header"},
+		{"var _ = yaml.Node{‸}", "yaml.Node"},
+		{"var _ = list.List[int]{‸}", "list.List"},
+		{"var _ = T{‸}", ""},
+		{"var _ = struct{}{‸}", ""},
+		{"var _ = tls.Config{‸}", ""},
+	}
+	for _, c := range cases {
+		src := decls + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		pkg, name, ok := cx.CompositeLitQualifiedType()
+		got := ""
+		if ok {
+			got = pkg + "." + name
+		}
+		if got != c.want || ok != (c.want != "") {
+			t.Errorf("CompositeLitQualifiedType() = (%q, %q, %v), want %q in %q", pkg, name, ok, c.want, c.src)
+		}
+	}
+}
+
+func TestAtCompositeLitStart(t *testing.T) {
+	cases := []struct {
+		src  string
+		want bool
+	}{
+		{"x := T{‸}", true},
+		{"x := &T{‸}", true},
+		{"x := T{\n\t\t‸\n\t}", true},
+		{"x := []T{{‸}}", true},
+		{"x := T{‸", true},
+		{"x := &T{‸", true},
+		{"x := []T{{‸", true},
+		{"x := T{A: 1,‸", false},
+		{"x := T{‸A: 1}", false},
+		{"x := T{A: 1, ‸}", false},
+		{"x := T{}‸", false},
+		{"x := func() {‸}", false},
+		{"if x {‸}", false},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		lit, ok := cx.AtCompositeLitStart()
+		if ok != c.want || (lit != nil) != c.want {
+			t.Errorf("AtCompositeLitStart() = (%v, %v), want %v in %q", lit != nil, ok, c.want, c.src)
+		}
+	}
+}
+
+func TestSliceLitElemIndex(t *testing.T) {
+	cases := []struct {
+		src   string
+		index int
+		ok    bool
+	}{
+		{"x := []int{‸}", 0, true},
+		{"x := []int{1, 2, ‸}", 2, true},
+		{"x := []int{1, ‸2, 3}", 1, true},
+		{"x := [3]string{\"a\", f(b, c), ‸}", 2, true},
+		{"x := []T{\n\t\t{A: 1},\n\t\t{A: 2},\n\t\t‸\n\t}", 2, true},
+		{"x := []pkg.T{{}, ‸}", 1, true},
+		{"x := Ints{1, ‸}", 1, true},
+		{"x := []string{2: \"b\", ‸}", 0, false},
+		{"x := T{A: 1, ‸}", 0, false},
+		{"x := map[string]int{‸}", 0, false},
+	}
+	for _, c := range cases {
+		src := "package p\ntype Ints []int\ntype T struct{ A int }\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		index, ok := cx.SliceLitElemIndex()
+		if index != c.index || ok != c.ok {
+			t.Errorf("SliceLitElemIndex() = (%d, %v), want (%d, %v) in %q", index, ok, c.index, c.ok, c.src)
+		}
+	}
+}
+
+func TestCompositeLitElemCount(t *testing.T) {
+	cases := []struct {
+		src   string
+		count int
+		ok    bool
+	}{
+		{"x := []T{‸}", 0, true},
+		{"x := []T{\n\t\t{A: 1},\n\t\t{A: 2, B: []int{1, 2, 3}},\n\t\t‸\n\t}", 2, true},
+		{"x := []T{\n\t\t{A: 1},\n\t\t{‸},\n\t\t{A: 3},\n\t}", 0, true},
+		{"x := []T{\n\t\t{A: 1},\n\t\t// {A: 2},\n\t\t‸\n\t}", 1, true},
+		{"x := map[string]int{\"a\": 1, \"b\": 2, ‸}", 2, true},
+		{"x := f(a, b, ‸)", 0, false},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		count, ok := cx.CompositeLitElemCount()
+		if count != c.count || ok != c.ok {
+			t.Errorf("CompositeLitElemCount() = (%d, %v), want (%d, %v) in %q", count, ok, c.count, c.ok, c.src)
+		}
+	}
+}
+
+func TestCompositeLitNeedsTrailingComma(t *testing.T) {
+	cases := []struct {
+		src   string
+		after string
+		ok    bool
+	}{
+		{"x := T{\n\t\tA: 1,\n\t\tB: 2‸\n\t}", "B: 2", true},
+		{"x := []int{\n\t\t1,\n\t\tf(2, 3)‸ // note, really\n\t}", "f(2, 3)", true},
+		{"x := []T{\n\t\t{A: 1}‸\n\t}", "{A: 1}", true},
+		{"x := []T{\n\t\t{A: 1},\n\t\t‸\n\t}", "", false},
+		{"x := T{\n\t\tA: 1, B: 2‸}", "", false},
+		{"x := T{A: 1, B: 2‸}", "", false},
+		{"x := T{\n\t\t‸\n\t}", "", false},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		pos, ok := cx.CompositeLitNeedsTrailingComma()
+		before := ""
+		if ok {
+			before = string(cx.Src[:pos])
+		}
+		if !strings.HasSuffix(before, c.after) || ok != c.ok {
+			t.Errorf("CompositeLitNeedsTrailingComma() = (%d, %v), want (after `%s`, %v) in %q", pos, ok, c.after, c.ok, c.src)
+		}
+	}
+}
+
+func TestCompositeLitTypeFromContext(t *testing.T) {
+	decls := "package p\ntype Item struct{ Tags []Tag }\ntype Tag struct{}\ntype Config struct{}\n"
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"var items []Item\n\titems = append(items, {‸})", "Item"},
+		{"var items []*Item\n\titems = append(items, {‸})", "Item"},
+		{"var items []Item\n\titems = append(items, Item{Tags: {{‸}}})", "Tag"},
+		{"items := []Item{}\n\titems = append(items, Item{‸})", "Item"},
+		{"var cfg Config = {‸}", "Config"},
+		{"var cfg *Config\n\tcfg = {‸}", "Config"},
+		{"cfg := {‸}", ""},
+		{"items = append(items, {‸})", ""},
+	}
+	for _, c := range cases {
+		src := decls + "func f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		typ, ok := cx.CompositeLitTypeFromContext()
+		got := ""
+		if typ != nil {
+			got, _ = cx.Print(typ)
+		}
+		if got != c.want || ok != (c.want != "") {
+			t.Errorf("CompositeLitTypeFromContext() = (`%s`, %v), want (`%s`, %v) in %q", got, ok, c.want, c.want != "", c.src)
+		}
+	}
+
+	src := decls + "func f() (int, *Config) {\n\treturn 0, {‸}\n}\n"
+	cx := newTestCurCtx(t, src)
+	typ, ok := cx.CompositeLitTypeFromContext()
+	if got, _ := cx.Print(typ); !ok || got != "Config" {
+		t.Errorf("CompositeLitTypeFromContext() = (`%s`, %v), want (`Config`, true) in %q", got, ok, src)
+	}
+}
+
+func TestCompositeTypeHeaderContext(t *testing.T) {
+	cases := []struct {
+		src  string
+		kind CompositeKind
+	}{
+		{"x := []‸{}", SliceComposite},
+		{"x := []‸", SliceComposite},
+		{"x := []T‸{}", SliceComposite},
+		{"x := []*‸{}", SliceComposite},
+		{"x := [3]‸{}", ArrayComposite},
+		{"x := [...]‸{}", ArrayComposite},
+		{"x := map[string]‸{}", MapComposite},
+		{"x := map[‸]int{}", MapComposite},
+		{"x := [][]‸{}", SliceComposite},
+		{"f([]‸{})", SliceComposite},
+		{"x := [‸3]T{}", UnknownComposite},
+		{"x := []T{‸}", UnknownComposite},
+		{"var x []‸", UnknownComposite},
+		{"x := ‸[]T{}", UnknownComposite},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		kind, ok := cx.CompositeTypeHeaderContext()
+		if kind != c.kind || ok != (c.kind != UnknownComposite) {
+			t.Errorf("CompositeTypeHeaderContext() = (%v, %v), want (%v, %v) in %q", kind, ok, c.kind, c.kind != UnknownComposite, c.src)
+		}
+	}
+}
+
+func TestFieldTypeForKey(t *testing.T) {
+	decls := "package p\ntype Config struct {\n\tDebug bool\n\tTLS   *TLSConfig\n\tio.Writer\n}\ntype TLSConfig struct{}\n"
+	cases := []struct {
+		src  string
+		key  string
+		want string
+	}{
+		{"var _ = Config{Debug: ‸}", "Debug", "bool"},
+		{"var _ = Config{Debug: true, TLS: ‸}", "TLS", "*TLSConfig"},
+		{"var _ = Config{Writer: ‸}", "Writer", "io.Writer"},
+		{"var _ = []Config{{Debug: ‸}}", "Debug", "bool"},
+		{"var _ = Config{Debug: true,‸", "TLS", "*TLSConfig"},
+		{"var _ = Config{Debug: ‸}", "Missing", ""},
+		{"var _ = Config{true, ‸}", "TLS", ""},
+		{"var _ = tls.Config{MinVersion: ‸}", "MinVersion", ""},
+	}
+	for _, c := range cases {
+		src := decls + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		typ, ok := cx.FieldTypeForKey(c.key)
+		got := ""
+		if typ != nil {
+			got, _ = cx.Print(typ)
+		}
+		if got != c.want || ok != (c.want != "") {
+			t.Errorf("FieldTypeForKey(%q) = (`%s`, %v), want (`%s`, %v) in %q", c.key, got, ok, c.want, c.want != "", c.src)
+		}
+	}
+}
+
+func TestCompositeLitDepth(t *testing.T) {
+	cases := []struct {
+		src  string
+		want int
+	}{
+		{"x := T{‸}", 1},
+		{"x := Server{TLS: TLSConfig{‸}}", 2},
+		{"x := []*T{&T{Tags: []Tag{{‸}}}}", 4},
+		{"x := T{A: f(U{‸})}", 1},
+		{"x := T{}‸", 0},
+		{"x := f(‸)", 0},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		if got := cx.CompositeLitDepth(); got != c.want {
+			t.Errorf("CompositeLitDepth() = %d, want %d in %q", got, c.want, c.src)
+		}
+	}
+}
+
+func TestCompositeFieldPath(t *testing.T) {
+	decls := "package p\ntype Config struct{ Servers []Server; ByName map[string]Server }\ntype Server struct{ Name string; TLS TLSConfig }\ntype TLSConfig struct{ Cert string }\n"
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"var _ = Server{TLS: TLSConfig{Cert: ‸}}", "Server.TLS.Cert"},
+		{"var _ = Server{TLS: {Cert: ‸}}", "Server.TLS.Cert"},
+		{"var _ = Server{TLS: TLSConfig{‸}}", "Server.TLS"},
+		{"var _ = Config{Servers: []Server{{Name: ‸}}}", "Config.Servers[0].Name"},
+		{"var _ = Config{Servers: []Server{{}, {TLS: {Cert: ‸}}}}", "Config.Servers[1].TLS.Cert"},
+		{"var _ = Config{Servers: []Server{\n\t{},\n\t‸\n}}", "Config.Servers[1]"},
+		{"var _ = Config{ByName: {\"a\": {Name: ‸}}}", "Config.ByName[\"a\"].Name"},
+		{"var _ = []Server{{}, {Name: ‸}}", "[1].Name"},
+		{"var _ = tls.Config{MinVersion: ‸}", "tls.Config.MinVersion"},
+		{"var _ = Server{‸}", "Server"},
+		{"var _ = ‸1", "-"},
+	}
+	for _, c := range cases {
+		src := decls + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		path, ok := cx.CompositeFieldPath()
+		got := "-"
+		if ok {
+			got = strings.Join(path, ".")
+		}
+		if got != c.want {
+			t.Errorf("CompositeFieldPath() = %q, want %q in %q", got, c.want, c.src)
+		}
+	}
+}
+
+func TestMapLitKeyType(t *testing.T) {
+	decls := "package p\ntype Key struct{ ID int }\ntype Index map[Key]string\n"
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"var _ = map[string]int{‸}", "string"},
+		{"var _ = map[string]int{\"a\": 1, ‸}", "string"},
+		{"var _ = map[string]int{\"‸\": 1}", "string"},
+		{"var _ = Index{‸}", "Key"},
+		{"var _ = map[string]int{‸", "string"},
+		{"var _ = map[string]int{\"a\": 1,‸", "string"},
+		{"var _ = map[string]map[Key]int{\"a\": {‸}}", "Key"},
+		{"var _ = map[string]int{\"a\": ‸}", ""},
+		{"var _ = map[string]Key{\"a\": {‸}}", ""},
+		{"var _ = []string{‸}", ""},
+		{"var _ = pkg.Map{‸}", ""},
+	}
+	for _, c := range cases {
+		src := decls + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		typ, ok := cx.MapLitKeyType()
+		got := ""
+		if typ != nil {
+			got, _ = cx.Print(typ)
+		}
+		if got != c.want || ok != (c.want != "") {
+			t.Errorf("MapLitKeyType() = (`%s`, %v), want (`%s`, %v) in %q", got, ok, c.want, c.want != "", c.src)
+		}
+	}
+}
+
+func TestCompositeLitInReturn(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"return Response{‸}", "Response"},
+		{"return Response{Code: ‸}", "Response"},
+		{"return {‸}", "Response"},
+		{"return Other{‸}", "Response"},
+		{"return Response{Header: Header{‸}}", "Response"},
+		{"return f(Response{‸})", ""},
+		{"x := Response{‸}", ""},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() Response {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		typ, ok := cx.CompositeLitInReturn()
+		got := ""
+		if typ != nil {
+			got, _ = cx.Print(typ)
+		}
+		if got != c.want || ok != (c.want != "") {
+			t.Errorf("CompositeLitInReturn() = (`%s`, %v), want (`%s`, %v) in %q", got, ok, c.want, c.want != "", c.src)
+		}
+	}
+
+	src := "package p\nfunc f() (*Response, error) {\n\treturn &Response{‸}, nil\n}\n"
+	cx := newTestCurCtx(t, src)
+	typ, ok := cx.CompositeLitInReturn()
+	if got, _ := cx.Print(typ); !ok || got != "Response" {
+		t.Errorf("CompositeLitInReturn() = (`%s`, %v), want (`Response`, true) in %q", got, ok, src)
+	}
+	src = "package p\nfunc f() (error, *Response) {\n\treturn nil, &{‸}\n}\n"
+	cx = newTestCurCtx(t, src)
+	typ, ok = cx.CompositeLitInReturn()
+	if got, _ := cx.Print(typ); !ok || got != "Response" {
+		t.Errorf("CompositeLitInReturn() = (`%s`, %v), want (`Response`, true) in %q", got, ok, src)
+	}
+}
+
+func TestInTestCaseTable(t *testing.T) {
+	cases := []struct {
+		pkg    string
+		src    string
+		fields string
+	}{
+		{"p_test", "tests := []struct{ name, want string }{\n\t\t‸\n\t}", "name want"},
+		{"p_test", "tests := []struct {\n\t\tin  int\n\t\tout *T\n\t}{\n\t\t{1, nil},\n\t\t‸\n\t}", "in out"},
+		{"p_test", "tests := []struct{ name, want string }{\n\t\t{name: ‸},\n\t}", "name want"},
+		{"p_test", "tests := []struct{ in []int }{\n\t\t{in: []int{‸}},\n\t}", ""},
+		{"p_test", "tests := []T{\n\t\t‸\n\t}", ""},
+		{"p_test", "tests := map[string]struct{ want string }{\n\t\t‸\n\t}", ""},
+		{"p", "tests := []struct{ name, want string }{\n\t\t‸\n\t}", ""},
+	}
+	for _, c := range cases {
+		src := "package " + c.pkg + "\nfunc TestF(t *testing.T) {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		fields, ok := cx.InTestCaseTable()
+		names := []string{}
+		for _, f := range fields {
+			names = append(names, f.Name)
+		}
+		got := strings.Join(names, " ")
+		if got != c.fields || ok != (c.fields != "") {
+			t.Errorf("InTestCaseTable() = (`%s`, %v), want (`%s`, %v) in %q", got, ok, c.fields, c.fields != "", c.src)
+		}
+	}
+}
+
+func TestCompositeKeyExists(t *testing.T) {
+	cases := []struct {
+		src    string
+		key    string
+		exists bool
+	}{
+		{"T{Name: \"a\", ‸}", "Name", true},
+		{"T{Name: \"a\", ‸}", "Age", false},
+		{"T{Name: \"a\",‸", "Name", true},
+		{"T{Name: \"a\",‸", "Age", false},
+		{"T{Na‸}", "Na", false},
+		{"T{Name‸: \"a\"}", "Name", false},
+		{"map[string]int{\"a\": 1, ‸}", `"a"`, true},
+		{"map[string]int{`a`: 1, ‸}", `"a"`, true},
+		{"map[string]int{\"a\": 1, ‸}", `"b"`, false},
+		{"map[int]bool{1: true, 0x2: false, ‸}", "1", true},
+		{"map[int]bool{1: true, 0x2: false, ‸}", "2", false},
+		{"map[Color]int{pkg.Red: 1, ‸}", "pkg.Red", true},
+		{"map[Color]int{Red: 1, ‸}", "Red", true},
+		{"map[string]int{f(): 1, ‸}", "f()", false},
+		{"[]int{0: 1, ‸}", "0", true},
+		{"f(Name, ‸)", "Name", false},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\tx := " + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		if got := cx.CompositeKeyExists(c.key); got != c.exists {
+			t.Errorf("CompositeKeyExists(%q) = %v, want %v in %q", c.key, got, c.exists, c.src)
+		}
+	}
+}
+
+func TestCompositeLitTypeText(t *testing.T) {
+	decls := "package p\ntype Server struct {\n\tListen struct{ Addr string }\n\tOpts   struct {\n\t\tDebug bool\n\t\tLevel int\n\t}\n}\n"
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"x := pkg.Config{‸}", "pkg.Config"},
+		{"x := []*Server{{‸}}", "Server"},
+		{"x := Server{Listen: {‸}}", "struct{ Addr string }"},
+		{"x := Server{Opts: {‸}}", "struct { Debug bool; Level int }"},
+		{"x := map[string][]int{\"a\": {‸}}", "[]int"},
+		{"x := struct {\n\t\tA int\n\t\tB struct {\n\t\t\tC int\n\t\t}\n\t}{‸}", "struct { A int; B struct{ C int } }"},
+		{"x := f(‸)", ""},
+	}
+	for _, c := range cases {
+		src := decls + "func f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		got, ok := cx.CompositeLitTypeText()
+		if got != c.want || ok != (c.want != "") {
+			t.Errorf("CompositeLitTypeText() = (`%s`, %v), want (`%s`, %v) in %q", got, ok, c.want, c.want != "", c.src)
+		}
+	}
+}
+
+func TestInheritedCompositeType(t *testing.T) {
+	decls := "package p\ntype Config struct {\n\tServers []Server\n\tByName  map[string]*Server\n}\ntype Server struct {\n\tName  string\n\tCerts []Cert\n\tTLS   struct{ Min int }\n}\ntype Cert struct{ File string }\ntype Point struct{ X, Y int }\ntype Line struct{ A, B Point }\n"
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"var _ = Config{Servers: {{‸}}}", "Server"},
+		{"var _ = Config{Servers: {{Certs: {{‸}}}}}", "Cert"},
+		{"var _ = Config{Servers: []Server{{Certs: {{File: ‸}}}}}", "Cert"},
+		{"var _ = Config{ByName: {\"a\": {Certs: {{‸}}}}}", "Cert"},
+		{"var _ = Config{ByName: {\"a\": {‸}}}", "Server"},
+		{"var _ = []Config{{Servers: {{TLS: {‸}}}}}", "struct{ Min int }"},
+		{"var _ = map[Point]string{{‸}: \"a\"}", "Point"},
+		{"var _ = Line{{1, 2}, {‸}}", "Point"},
+		{"var _ = []Line{{A: {1, 2}, B: {‸}}}", "Point"},
+		{"var _ = Config{Servers: {Server{‸}}}", ""},
+		{"var _ = Config{‸}", ""},
+		{"var _ = []pkg.Config{{Servers: {{‸}}}}", ""},
+		{"var _ = Line{{1, 2}, {3, 4}, {‸}}", ""},
+	}
+	for _, c := range cases {
+		src := decls + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		typ, ok := cx.InheritedCompositeType()
+		got := ""
+		if typ != nil {
+			got, _ = cx.Print(typ)
+		}
+		if got != c.want || ok != (c.want != "") {
+			t.Errorf("InheritedCompositeType() = (`%s`, %v), want (`%s`, %v) in %q", got, ok, c.want, c.want != "", c.src)
+		}
+	}
+}
+
+func TestCompositeLitAddressed(t *testing.T) {
+	cases := []struct {
+		src  string
+		want bool
+	}{
+		{"x := &T{‸}", true},
+		{"x := &T{‸", true},
+		{"x := T{‸}", false},
+		{"x := []*T{{‸}}", true},
+		{"x := []*T{&T{‸}}", true},
+		{"x := []*T{T{‸}}", false},
+		{"x := []T{{‸}}", false},
+		{"x := map[string]*T{\"a\": {‸}}", true},
+		{"x := &T{A: []int{‸}}", false},
+		{"x := f(‸)", false},
+	}
+	for _, c := range cases {
+		src := "package p\ntype T struct{ A []int }\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		if got := cx.CompositeLitAddressed(); got != c.want {
+			t.Errorf("CompositeLitAddressed() = %v, want %v in %q", got, c.want, c.src)
+		}
+	}
+}
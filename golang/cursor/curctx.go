@@ -47,6 +47,11 @@ type CurCtx struct {
 	Nodes      []ast.Node
 	Node       ast.Node
 
+	// srcPos is the cursor position before it was adjusted to sit on the last thing on the line
+	srcPos int
+
+	memo *curMemo
+
 	printer struct {
 		*sync.Mutex
 		printer.Config
@@ -95,6 +100,15 @@ func cachedCx(mx *mg.Ctx, k interface{}) *CurCtx {
 }
 
 func fixSrcPos(mx *mg.Ctx, src []byte, pos int) ([]byte, int) {
+	if n := len(src); n != 0 && pos >= n {
+		// make sure the cursor is always on a byte in src,
+		// otherwise a cursor after the final newline ends up on the previous line
+		p := make([]byte, n+1)
+		copy(p, src)
+		p[n] = '\n'
+		src, pos = p, n
+	}
+
 	pos = mgutil.ClampPos(src, pos)
 	if len(src) == 0 || pos == 0 {
 		return src, pos
@@ -115,6 +129,7 @@ func newCurCtx(mx *mg.Ctx, src []byte, pos int) *CurCtx {
 	defer mx.Profile.Push("NewCurCtx").Pop()
 
 	src, pos = fixSrcPos(mx, src, pos)
+	srcPos := pos
 
 	// if we're at the end of the line, move the cursor onto the last thing on the line
 	space := func(r rune) bool { return r == ' ' || r == '\t' }
@@ -128,11 +143,13 @@ func newCurCtx(mx *mg.Ctx, src []byte, pos int) *CurCtx {
 	ll := mgutil.RepositionLeft(src, pos, func(r rune) bool { return r != '\n' })
 	lr := mgutil.RepositionRight(src, pos, func(r rune) bool { return r != '\n' })
 	cx := &CurCtx{
-		Ctx:  mx,
-		View: mx.View,
-		Line: bytes.TrimSpace(src[ll:lr]),
-		Src:  src,
-		Pos:  pos,
+		Ctx:    mx,
+		View:   mx.View,
+		Line:   bytes.TrimSpace(src[ll:lr]),
+		Src:    src,
+		Pos:    pos,
+		srcPos: srcPos,
+		memo:   &curMemo{m: map[interface{}]interface{}{}},
 	}
 	cx.printer.Mutex = &sync.Mutex{}
 	cx.printer.fset = token.NewFileSet()
@@ -150,6 +167,9 @@ func newCurCtx(mx *mg.Ctx, src []byte, pos int) *CurCtx {
 
 	if cx.Comment != nil {
 		cx.Scope |= CommentScope
+		if _, ok := cx.embedPattern(); ok {
+			cx.Scope |= EmbedScope
+		}
 	}
 	if cx.Doc != nil {
 		cx.Scope |= DocScope
@@ -162,6 +182,19 @@ func newCurCtx(mx *mg.Ctx, src []byte, pos int) *CurCtx {
 		return cx
 	}
 
+	if lit := cx.BasicLit; lit != nil && lit.Kind == token.STRING {
+		cx.Scope |= StringScope
+		if cx.ImportSpec != nil {
+			cx.Scope |= ImportPathScope
+		}
+		if _, ok := cx.printfFuncCtx(); ok {
+			cx.Scope |= FormatStringScope
+		}
+		cx.Scope |= cx.stringContextScope()
+	} else if _, ok := cx.scanImportPathPrefix(); ok {
+		cx.Scope |= ImportScope | ImportPathScope | StringScope
+	}
+
 	switch x := cx.Node.(type) {
 	case nil:
 		cx.Scope |= PackageScope
@@ -178,7 +211,7 @@ func newCurCtx(mx *mg.Ctx, src []byte, pos int) *CurCtx {
 	}
 
 	cx.Each(func(n ast.Node) {
-		switch n.(type) {
+		switch x := n.(type) {
 		case *ast.AssignStmt:
 			cx.Scope |= AssignmentScope
 		case *ast.SelectorExpr:
@@ -187,9 +220,65 @@ func newCurCtx(mx *mg.Ctx, src []byte, pos int) *CurCtx {
 			cx.Scope |= ReturnScope
 		case *ast.DeferStmt:
 			cx.Scope |= DeferScope
+		case *ast.GoStmt:
+			cx.Scope |= GoScope
+		case *ast.FuncLit:
+			if b := x.Body; b != nil && b.Lbrace < cx.TokenPos && cx.TokenPos <= b.Rbrace {
+				cx.Scope |= FuncLitScope
+			}
+		case *ast.InterfaceType:
+			if fl := x.Methods; fl != nil && fl.Opening < cx.TokenPos && cx.TokenPos <= fl.Closing {
+				cx.Scope |= InterfaceBodyScope
+			}
 		}
 	})
 
+	if kw, ok := cx.IncompleteStmtKeyword(); ok {
+		switch kw {
+		case token.RETURN:
+			cx.Scope |= ReturnScope
+		case token.DEFER:
+			cx.Scope |= DeferScope
+		case token.GO:
+			cx.Scope |= GoScope
+		}
+	}
+
+	switch kind, _ := cx.InDeferredOrGoClosure(); kind {
+	case DeferStmtKind:
+		cx.Scope |= FuncLitScope | BlockScope | DeferScope
+	case GoStmtKind:
+		cx.Scope |= FuncLitScope | BlockScope | GoScope
+	}
+
+	if cx.enclosingRangeBody() != nil {
+		cx.Scope |= RangeScope
+	}
+
+	if _, tok, ok := cx.deferredMethodCtx(); ok {
+		cx.Scope |= SelectorScope
+		if tok == token.GO {
+			cx.Scope |= GoScope
+		} else {
+			cx.Scope |= DeferScope
+		}
+	}
+
+	if open, _ := cx.typeParamList(); open.IsValid() {
+		cx.Scope |= TypeParamScope
+		if cx.inConstraintPos(open) {
+			cx.Scope |= ConstraintScope
+		}
+	}
+
+	if kind, _ := cx.TypeUseKind(); kind == CompositeLitTypeUse {
+		cx.Scope |= CompositeLitScope
+	}
+
+	if _, _, _, ok := cx.ChannelOp(); ok {
+		cx.Scope |= ChanScope
+	}
+
 	if gd := cx.GenDecl; gd != nil {
 		switch gd.Tok {
 		case token.IMPORT:
@@ -201,13 +290,6 @@ func newCurCtx(mx *mg.Ctx, src []byte, pos int) *CurCtx {
 		}
 	}
 
-	if lit := cx.BasicLit; lit != nil && lit.Kind == token.STRING {
-		cx.Scope |= StringScope
-		if cx.ImportSpec != nil {
-			cx.Scope |= ImportPathScope
-		}
-	}
-
 	// we want to allow `kw`, `kw name`, `kw (\n|\n)`
 	punct := func(r rune) bool { return r != ' ' && r != '\t' && !goutil.IsLetter(r) }
 	if cx.Scope == 0 && bytes.IndexFunc(cx.Line, punct) < 0 {
@@ -221,6 +303,10 @@ func newCurCtx(mx *mg.Ctx, src []byte, pos int) *CurCtx {
 		}
 	}
 
+	if cx.isStmtStart() {
+		cx.Scope |= StmtStartScope
+	}
+
 	exprOk := cx.Scope.Is(
 		AssignmentScope|
 			BlockScope|
@@ -342,6 +428,13 @@ func (cx *CurCtx) Print(x ast.Node) (string, error) {
 	return p.buf.String(), err
 }
 
+// inBrackets returns true if the cursor is between the brackets open and close.
+// The original cursor position is used because at the end of a line, e.g. `T{‸` or `f(‸`,
+// TokenPos is moved back onto the opening bracket. If close is invalid, the brackets are unclosed.
+func (cx *CurCtx) inBrackets(open, close token.Pos) bool {
+	return cx.TokenFile.Offset(open) < cx.srcPos && (!close.IsValid() || cx.srcPos <= cx.TokenFile.Offset(close))
+}
+
 func (cx *CurCtx) append(n ast.Node) {
 	// ignore bad nodes, they usually just make scope detection fail with no obvious benefit
 	switch n.(type) {
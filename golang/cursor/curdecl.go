@@ -0,0 +1,295 @@
+package cursor
+
+import (
+	"go/ast"
+	"go/token"
+	"margo.sh/golang/goutil"
+)
+
+// DeclKind describes the kind of a top-level declaration
+type DeclKind uint8
+
+const (
+	// UnknownDeclKind is the zero value, the cursor is not on a declaration's name
+	UnknownDeclKind DeclKind = iota
+
+	// ConstDeclKind is a const declaration e.g. `const C = 1`
+	ConstDeclKind
+
+	// VarDeclKind is a var declaration e.g. `var V int`
+	VarDeclKind
+
+	// TypeDeclKind is a type declaration e.g. `type T struct{}`
+	TypeDeclKind
+
+	// FuncDeclKind is a func declaration e.g. `func F()`
+	FuncDeclKind
+
+	// MethodDeclKind is a method declaration e.g. `func (t T) M()`
+	MethodDeclKind
+)
+
+// OnTopLevelDeclName returns the name and kind of the file-scope declaration
+// iff the cursor is on the identifier naming it e.g. `T` in `type T‸ struct{}`.
+// Uses of the name and local declarations e.g. `x` in `func f() { var x‸ int }` are not included.
+func (cx *CurCtx) OnTopLevelDeclName() (name string, kind DeclKind, ok bool) {
+	if name, isMethod := cx.FuncDeclName(); name != "" {
+		if isMethod {
+			return name, MethodDeclKind, true
+		}
+		return name, FuncDeclKind, true
+	}
+
+	n := len(cx.Nodes)
+	id, _ := cx.Node.(*ast.Ident)
+	if id == nil || n < 2 {
+		return "", UnknownDeclKind, false
+	}
+	switch cx.Nodes[n-2].(type) {
+	case *ast.ValueSpec, *ast.TypeSpec:
+	default:
+		return "", UnknownDeclKind, false
+	}
+	gd := cx.GenDecl
+	if gd == nil || n < 4 || cx.Nodes[n-3] != gd {
+		return "", UnknownDeclKind, false
+	}
+	if _, ok := cx.Nodes[n-4].(*ast.File); !ok {
+		return "", UnknownDeclKind, false
+	}
+	switch x := cx.Nodes[n-2].(type) {
+	case *ast.TypeSpec:
+		if x.Name == id {
+			return id.Name, TypeDeclKind, true
+		}
+	case *ast.ValueSpec:
+		for _, nm := range x.Names {
+			if nm != id {
+				continue
+			}
+			if gd.Tok == token.CONST {
+				return id.Name, ConstDeclKind, true
+			}
+			return id.Name, VarDeclKind, true
+		}
+	}
+	return "", UnknownDeclKind, false
+}
+
+// TypedConstContext returns the type of the const or var spec whose value the cursor is in
+// e.g. `Color` in `const X Color = ‸`, so values of that type can be offered.
+// ok is false if the spec has no explicit type e.g. `const X = ‸`, or the cursor is not in its values.
+func (cx *CurCtx) TypedConstContext() (typeName string, ok bool) {
+	var vs *ast.ValueSpec
+	if cx.GenDecl == nil || !cx.Set(&vs) {
+		return "", false
+	}
+	if vs.Type == nil || len(vs.Values) == 0 || cx.TokenPos <= vs.Type.End() {
+		return "", false
+	}
+	typeName, err := cx.Print(vs.Type)
+	return typeName, err == nil
+}
+
+// PrevSpec returns the spec immediately before the cursor in the enclosing grouped declaration
+// e.g. `var ( a int; ‸ )`.
+// If the cursor is on a spec, the spec before it is returned.
+func (cx *CurCtx) PrevSpec() (ast.Spec, bool) {
+	gd := cx.GenDecl
+	if gd == nil || !gd.Lparen.IsValid() || cx.TokenPos <= gd.Lparen || cx.TokenPos > gd.Rparen {
+		return nil, false
+	}
+	var prev ast.Spec
+	for _, spec := range gd.Specs {
+		if spec.End() > cx.TokenPos || goutil.NodeEnclosesPos(spec, cx.TokenPos) {
+			break
+		}
+		prev = spec
+	}
+	return prev, prev != nil
+}
+
+// IotaContinuation returns the spec before the cursor iff the cursor is at a new spec position
+// in a const group whose implicit values are based on iota e.g. `const ( A = iota; B; ‸ )`.
+// A spec with only a name e.g. `C‸` is still considered new.
+func (cx *CurCtx) IotaContinuation() (prevSpec *ast.ValueSpec, ok bool) {
+	gd := cx.GenDecl
+	if gd == nil || gd.Tok != token.CONST {
+		return nil, false
+	}
+	for _, spec := range gd.Specs {
+		vs, _ := spec.(*ast.ValueSpec)
+		if vs != nil && goutil.NodeEnclosesPos(vs, cx.TokenPos) && (vs.Type != nil || len(vs.Values) != 0) {
+			return nil, false
+		}
+	}
+	prev, _ := cx.PrevSpec()
+	prevSpec, _ = prev.(*ast.ValueSpec)
+	if prevSpec == nil {
+		return nil, false
+	}
+
+	// the values of specs without values are implicitly repeated from the last spec with values
+	var values []ast.Expr
+	for _, spec := range gd.Specs {
+		if vs, _ := spec.(*ast.ValueSpec); vs != nil && len(vs.Values) != 0 {
+			values = vs.Values
+		}
+		if spec == prev {
+			break
+		}
+	}
+	for _, x := range values {
+		usesIota := false
+		ast.Inspect(x, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok && id.Name == "iota" {
+				usesIota = true
+			}
+			return !usesIota
+		})
+		if usesIota {
+			return prevSpec, true
+		}
+	}
+	return nil, false
+}
+
+// GenDeclRange returns the range of the import, var, const or type declaration enclosing the cursor,
+// from the keyword to the closing paren, or the end of the spec if the declaration isn't grouped.
+// Use cx.TokenFile.Offset to convert it to byte offsets in cx.Src.
+//
+// ok is false if the declaration is incomplete e.g. its closing paren is missing.
+func (cx *CurCtx) GenDeclRange() (goutil.PosEnd, bool) {
+	gd := cx.GenDecl
+	switch {
+	case gd == nil:
+		return goutil.PosEnd{}, false
+	case gd.Lparen.IsValid() && !gd.Rparen.IsValid():
+		return goutil.PosEnd{}, false
+	case !gd.Lparen.IsValid() && len(gd.Specs) == 0:
+		return goutil.PosEnd{}, false
+	}
+	return goutil.PosEnd{P: gd.Pos(), E: gd.End()}, true
+}
+
+// SpecComment is a spec in a grouped declaration and its trailing comment
+type SpecComment struct {
+	// Spec is the *ast.ImportSpec, *ast.ValueSpec or *ast.TypeSpec
+	Spec ast.Spec
+
+	// Comment is the comment on the same line after the spec e.g. `// x` in `a = 1 // x`, or nil
+	Comment *ast.CommentGroup
+
+	// AtCursor is true if the spec encloses the cursor
+	AtCursor bool
+}
+
+// GroupSpecsWithComments returns the specs of the grouped declaration enclosing the cursor
+// e.g. `const ( a = 1 // x; b = 2 )`, along with their trailing comments.
+// ok is false if the cursor is not in a grouped declaration.
+func (cx *CurCtx) GroupSpecsWithComments() ([]SpecComment, bool) {
+	gd := cx.GenDecl
+	if gd == nil || !gd.Lparen.IsValid() {
+		return nil, false
+	}
+	l := make([]SpecComment, len(gd.Specs))
+	for i, spec := range gd.Specs {
+		sc := SpecComment{
+			Spec:     spec,
+			AtCursor: goutil.NodeEnclosesPos(spec, cx.TokenPos),
+		}
+		switch x := spec.(type) {
+		case *ast.ImportSpec:
+			sc.Comment = x.Comment
+		case *ast.ValueSpec:
+			sc.Comment = x.Comment
+		case *ast.TypeSpec:
+			sc.Comment = x.Comment
+		}
+		l[i] = sc
+	}
+	return l, true
+}
+
+// InterfaceAssertion returns the interface and type names of the interface conformance assertion
+// enclosing the cursor e.g. `var _ io.Reader = (*T)(nil)`.
+//
+// The pointer forms `(*T)(nil)`, `&T{}` and `new(T)` return typeName `*T`
+// and the value form `T{}` returns typeName `T`.
+// Qualified types keep their package name e.g. `*pkg.T` for `(*pkg.T)(nil)`,
+// and type arguments are dropped e.g. `T` for `T[int]{}`.
+func (cx *CurCtx) InterfaceAssertion() (ifaceName string, typeName string, ok bool) {
+	var vs *ast.ValueSpec
+	if !cx.Set(&vs) || vs.Type == nil || len(vs.Names) != 1 || vs.Names[0].Name != "_" || len(vs.Values) != 1 {
+		return "", "", false
+	}
+	typ, ptr := assertedType(vs.Values[0])
+	if se, ok := typeNameExpr(typ).(*ast.SelectorExpr); ok {
+		if pkg, ok := se.X.(*ast.Ident); ok {
+			typeName = pkg.Name + "." + se.Sel.Name
+		}
+	} else if id := typeNameIdent(typ); id != nil {
+		typeName = id.Name
+	}
+	if typeName == "" {
+		return "", "", false
+	}
+	ifaceName, err := cx.Print(vs.Type)
+	if err != nil {
+		return "", "", false
+	}
+	if ptr {
+		typeName = "*" + typeName
+	}
+	return ifaceName, typeName, true
+}
+
+// typeNameExpr returns typ without its type arguments and parens e.g. `pkg.T` for `(pkg.T[int])`
+func typeNameExpr(typ ast.Expr) ast.Expr {
+	for {
+		switch x := typ.(type) {
+		case *ast.IndexExpr:
+			typ = x.X
+		case *ast.IndexListExpr:
+			typ = x.X
+		case *ast.ParenExpr:
+			typ = x.X
+		default:
+			return typ
+		}
+	}
+}
+
+// assertedType returns the type of the value in an interface conformance assertion
+func assertedType(x ast.Expr) (typ ast.Expr, ptr bool) {
+	if call, ok := x.(*ast.CallExpr); ok && len(call.Args) == 1 {
+		// (*T)(nil)
+		if p, ok := call.Fun.(*ast.ParenExpr); ok {
+			if st, ok := p.X.(*ast.StarExpr); ok {
+				return st.X, true
+			}
+		}
+	}
+	typ = exprType(x)
+	if st, ok := typ.(*ast.StarExpr); ok {
+		return st.X, true
+	}
+	return typ, false
+}
+
+// EnclosingFieldGroup returns the field enclosing the cursor e.g. `a, b int` in `func f(a, b‸ int)`,
+// and the index of the name the cursor is on, within the field's names.
+//
+// nameIndex is -1 if the field is unnamed e.g. `func f(int‸)`, or the cursor is not on a name
+// e.g. `func f(a, b in‸t)`.
+func (cx *CurCtx) EnclosingFieldGroup() (field *ast.Field, nameIndex int, ok bool) {
+	if !cx.Set(&field) {
+		return nil, -1, false
+	}
+	for i, id := range field.Names {
+		if goutil.NodeEnclosesPos(id, cx.TokenPos) {
+			return field, i, true
+		}
+	}
+	return field, -1, true
+}
@@ -0,0 +1,249 @@
+package cursor
+
+import (
+	"go/ast"
+	"strings"
+	"testing"
+)
+
+func TestTypedConstContext(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"const X Color = ‸", "Color"},
+		{"const X Color = R‸", "Color"},
+		{"const (\n\tA Color = 1\n\tB Color = ‸\n)", "Color"},
+		{"var c image.Color = ‸", "image.Color"},
+		{"func f() {\n\tconst k Kind = ‸\n}", "Kind"},
+		{"const X = ‸", ""},
+		{"const X Co‸lor = 1", ""},
+		{"const (\n\tA Color = iota\n\tB‸\n)", ""},
+		{"var x = T{A: ‸}", ""},
+	}
+	for _, c := range cases {
+		src := "package p\n" + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		typeName, ok := cx.TypedConstContext()
+		if typeName != c.want || ok != (c.want != "") {
+			t.Errorf("TypedConstContext() = (%q, %v), want (%q, %v) in %q", typeName, ok, c.want, c.want != "", c.src)
+		}
+	}
+}
+
+func TestOnTopLevelDeclName(t *testing.T) {
+	cases := []struct {
+		src  string
+		name string
+		kind DeclKind
+	}{
+		{"type T‸ struct{}", "T", TypeDeclKind},
+		{"type (\n\tA int\n\t‸B string\n)", "B", TypeDeclKind},
+		{"var a, b‸ int", "b", VarDeclKind},
+		{"const (\n\tX‸ = iota\n)", "X", ConstDeclKind},
+		{"func F‸() {}", "F", FuncDeclKind},
+		{"func (t T) M‸() {}", "M", MethodDeclKind},
+		{"var a = b‸", "", UnknownDeclKind},
+		{"type T struct{ F‸ int }", "", UnknownDeclKind},
+		{"func (t‸ T) M() {}", "", UnknownDeclKind},
+		{"func f() {\n\tvar x‸ int\n}", "", UnknownDeclKind},
+		{"func f() {\n\ttype L‸ int\n}", "", UnknownDeclKind},
+		{"import f‸mt \"fmt\"", "", UnknownDeclKind},
+	}
+	for _, c := range cases {
+		src := "package p\n" + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		name, kind, ok := cx.OnTopLevelDeclName()
+		if name != c.name || kind != c.kind || ok != (c.name != "") {
+			t.Errorf("OnTopLevelDeclName() = (%q, %v, %v), want (%q, %v, %v) in %q", name, kind, ok, c.name, c.kind, c.name != "", c.src)
+		}
+	}
+}
+
+func TestGenDeclRange(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"package p\nimport (\n\t\"fmt\"\n\t\"o‸s\"\n)\n", "import (\n\t\"fmt\"\n\t\"os\"\n)"},
+		{"package p\nimport \"o‸s\"\n", "import \"os\""},
+		{"package p\n// doc\nvar (\n\ta = 1\n\t‸\n)\n", "var (\n\ta = 1\n\t\n)"},
+		{"package p\nconst c = ‸1 // x\n", "const c = 1"},
+		{"package p\ntype T struct {\n\tN ‸int\n}\n", "type T struct {\n\tN int\n}"},
+		{"package p\nfunc f() {\n\tvar x = ‸1\n}\n", "var x = 1"},
+		{"package p\nfunc f() {\n\tx := ‸1\n}\n", ""},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		pe, ok := cx.GenDeclRange()
+		got := ""
+		if ok {
+			got = string(cx.Src[cx.TokenFile.Offset(pe.Pos()):cx.TokenFile.Offset(pe.End())])
+		}
+		if got != c.want || ok != (c.want != "") {
+			t.Errorf("GenDeclRange() = (%q, %v), want (%q, %v) in %q", got, ok, c.want, c.want != "", c.src)
+		}
+	}
+}
+
+func TestEnclosingFieldGroup(t *testing.T) {
+	cases := []struct {
+		src       string
+		field     string
+		nameIndex int
+	}{
+		{"package p\nfunc f(a, b‸ int) {}\n", "a, b int", 1},
+		{"package p\nfunc f(‸a, b int) {}\n", "a, b int", 0},
+		{"package p\nfunc f(a, b in‸t) {}\n", "a, b int", -1},
+		{"package p\nfunc f(x string, a, b, c‸ int) {}\n", "a, b, c int", 2},
+		{"package p\nfunc f(in‸t, string) {}\n", "int", -1},
+		{"package p\nfunc f() (n‸ int, err error) {}\n", "n int", 0},
+		{"package p\ntype T struct {\n\tX, Y‸ float64\n}\n", "X, Y float64", 1},
+		{"package p\nfunc f() {\n\t‸\n}\n", "", -1},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		field, nameIndex, ok := cx.EnclosingFieldGroup()
+		got := ""
+		if field != nil {
+			names := []string{}
+			for _, id := range field.Names {
+				names = append(names, id.Name)
+			}
+			typ, _ := cx.Print(field.Type)
+			got = strings.TrimSpace(strings.Join(names, ", ") + " " + typ)
+		}
+		if got != c.field || nameIndex != c.nameIndex || ok != (c.field != "") {
+			t.Errorf("EnclosingFieldGroup() = (`%s`, %d, %v), want (`%s`, %d, %v) in %q", got, nameIndex, ok, c.field, c.nameIndex, c.field != "", c.src)
+		}
+	}
+}
+
+func TestGroupSpecsWithComments(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"package p\nconst (\n\tA = 1 // a\n\tBB = ‸2\n\tC = 3 /* c */\n)\n", "A=// a|*BB=|C=/* c */"},
+		{"package p\nimport (\n\t\"fmt\" // fmt‸\n\t\"os\"\n)\n", "\"fmt\"=// fmt|\"os\"="},
+		{"package p\ntype (\n\tT int // t\n\tU‸ string\n)\n", "T=// t|*U="},
+		{"package p\nconst A = ‸1 // a\n", ""},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		l, ok := cx.GroupSpecsWithComments()
+		parts := []string{}
+		for _, sc := range l {
+			var name string
+			switch x := sc.Spec.(type) {
+			case *ast.ImportSpec:
+				name = x.Path.Value
+			case *ast.ValueSpec:
+				name = x.Names[0].Name
+			case *ast.TypeSpec:
+				name = x.Name.Name
+			}
+			if sc.AtCursor {
+				name = "*" + name
+			}
+			cmnt := ""
+			if sc.Comment != nil {
+				cmnt = sc.Comment.List[0].Text
+			}
+			parts = append(parts, name+"="+cmnt)
+		}
+		if got := strings.Join(parts, "|"); got != c.want || ok != (c.want != "") {
+			t.Errorf("GroupSpecsWithComments() = (%q, %v), want (%q, %v) in %q", got, ok, c.want, c.want != "", c.src)
+		}
+	}
+}
+
+func TestIotaContinuation(t *testing.T) {
+	cases := []struct {
+		src  string
+		prev string
+	}{
+		{"const (\n\tA = iota\n\tB\n\t‸\n)", "B"},
+		{"const (\n\tA = iota\n\t‸\n)", "A"},
+		{"const (\n\tA Kind = 1 << iota\n\tB\n\tC‸\n)", "B"},
+		{"const (\n\t_ = iota\n\tKB = 1 << (10 * iota)\n\t‸\n)", "KB"},
+		{"const (\n\tA = iota\n\tB\n\tC = ‸5\n)", ""},
+		{"const (\n\tA = 1\n\tB\n\t‸\n)", ""},
+		{"const (\n\tA = iota\n\tB = 10\n\t‸\n)", ""},
+		{"var (\n\tA = iota\n\t‸\n)", ""},
+		{"const (\n\t‸\n)", ""},
+	}
+	for _, c := range cases {
+		src := "package p\n" + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		prev, ok := cx.IotaContinuation()
+		got := ""
+		if prev != nil {
+			got = prev.Names[0].Name
+		}
+		if got != c.prev || ok != (c.prev != "") {
+			t.Errorf("IotaContinuation() = (%q, %v), want (%q, %v) in %q", got, ok, c.prev, c.prev != "", c.src)
+		}
+	}
+}
+
+func TestInterfaceAssertion(t *testing.T) {
+	cases := []struct {
+		src      string
+		iface    string
+		typeName string
+	}{
+		{"var _ io.Reader = (*T)(nil‸)", "io.Reader", "*T"},
+		{"var _ io.Reader = &T{}‸", "io.Reader", "*T"},
+		{"var _ io.Reader = new(T)‸", "io.Reader", "*T"},
+		{"var _ fmt.Stringer = T{}‸", "fmt.Stringer", "T"},
+		{"var _ Iface = G[int]{}‸", "Iface", "G"},
+		{"var _ io.Reader = (*pkg.T)(nil‸)", "io.Reader", "*pkg.T"},
+		{"var _ io.Reader = pkg.T{}‸", "io.Reader", "pkg.T"},
+		{"var x io.Reader = (*T)(nil‸)", "", ""},
+		{"var _ = (*T)(nil‸)", "", ""},
+		{"var _ io.Reader = f()‸", "", ""},
+	}
+	for _, c := range cases {
+		src := "package p\n" + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		iface, typeName, ok := cx.InterfaceAssertion()
+		if iface != c.iface || typeName != c.typeName || ok != (c.iface != "") {
+			t.Errorf("InterfaceAssertion() = (%q, %q, %v), want (%q, %q, %v) in %q", iface, typeName, ok, c.iface, c.typeName, c.iface != "", c.src)
+		}
+	}
+}
+
+func TestPrevSpec(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"var (\n\t‸\n)", ""},
+		{"var (\n\ta int\n\t‸\n)", "a"},
+		{"var (\n\ta int\n\tb string\n\t‸\n)", "b"},
+		{"var (\n\ta int\n\tb‸ string\n)", "a"},
+		{"var (\n\ta‸ int\n\tb string\n)", ""},
+		{"const (\n\tA = iota\n\tB\n\t‸\n)", "B"},
+		{"type (\n\tT int\n\t‸\n)", "T"},
+		{"import (\n\t\"fmt\"\n\t‸\n)", "\"fmt\""},
+		{"var a‸ int", ""},
+	}
+	for _, c := range cases {
+		src := "package p\n" + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		spec, ok := cx.PrevSpec()
+		got := ""
+		switch x := spec.(type) {
+		case *ast.ValueSpec:
+			got = x.Names[0].Name
+		case *ast.TypeSpec:
+			got = x.Name.Name
+		case *ast.ImportSpec:
+			got = x.Path.Value
+		}
+		if got != c.want || ok != (c.want != "") {
+			t.Errorf("PrevSpec() = (%q, %v), want (%q, %v) in %q", got, ok, c.want, c.want != "", c.src)
+		}
+	}
+}
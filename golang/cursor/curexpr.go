@@ -0,0 +1,297 @@
+package cursor
+
+import (
+	"go/ast"
+	"go/scanner"
+	"go/token"
+)
+
+// ConversionContext returns the type being converted to iff the cursor is in the argument of a conversion
+// e.g. `[]byte(‸)`, `map[string]int(‸)` or `string(‸)`.
+//
+// Only conversions that are syntactically unambiguous are recognised:
+// composite types (slice, array, map, chan, func, parenthesised pointer) and predeclared types.
+// Composite literals e.g. `[]T{‸}` are not conversions.
+func (cx *CurCtx) ConversionContext() (typ ast.Expr, ok bool) {
+	call := cx.enclosingCallArgs()
+	if call == nil || !isTypeExpr(call.Fun) {
+		return nil, false
+	}
+	args := 0
+	for _, x := range call.Args {
+		// the parser fills in the missing `)` of `T(x‸` with a BadExpr
+		if badExprNil(x) != nil {
+			args++
+		}
+	}
+	if args > 1 {
+		return nil, false
+	}
+	return call.Fun, true
+}
+
+// TypeUseKind describes how a type is used in an expression, for constructor-like expressions
+type TypeUseKind uint8
+
+const (
+	// UnknownTypeUse is the zero value, the callee can't be resolved to a type or function
+	UnknownTypeUse TypeUseKind = iota
+
+	// ConversionTypeUse is a conversion e.g. `T(‸)`
+	ConversionTypeUse
+
+	// CompositeLitTypeUse is a composite literal e.g. `T{‸}`
+	CompositeLitTypeUse
+)
+
+// TypeUseKind returns whether the cursor is in the parens of a conversion e.g. `T(‸)`
+// or the braces of a composite literal e.g. `T{‸}`.
+// ok is false if the cursor is in neither, or the parens are those of a function call.
+//
+// The original cursor position is used, so the kind is reported as soon as `(` or `{` is typed,
+// even if the closing bracket is missing and the cursor is at the end of the line.
+// Callees are resolved as for CalleeIsType; kind is UnknownTypeUse if that's not possible.
+func (cx *CurCtx) TypeUseKind() (kind TypeUseKind, ok bool) {
+	in := cx.inBrackets
+	for i := len(cx.Nodes) - 1; i >= 0; i-- {
+		switch x := cx.Nodes[i].(type) {
+		case *ast.CompositeLit:
+			if in(x.Lbrace, x.Rbrace) {
+				return CompositeLitTypeUse, true
+			}
+		case *ast.CallExpr:
+			if !x.Lparen.IsValid() || !in(x.Lparen, x.Rparen) {
+				continue
+			}
+			var yes, known bool
+			switch fun := x.Fun.(type) {
+			case *ast.FuncLit:
+				known = true
+			case *ast.Ident:
+				yes, known = cx.identIsType(fun.Name)
+			default:
+				yes = isTypeExpr(fun)
+				known = yes
+			}
+			switch {
+			case !known:
+				return UnknownTypeUse, true
+			case yes:
+				return ConversionTypeUse, true
+			}
+			return UnknownTypeUse, false
+		case *ast.FuncLit, ast.Stmt:
+			return UnknownTypeUse, false
+		}
+	}
+	return UnknownTypeUse, false
+}
+
+// CalleeIsType reports whether the callee of the call enclosing the cursor is a type i.e.
+// whether `T(‸)` is a conversion or a function call.
+//
+// The callee is resolved syntactically against the declarations in the file,
+// and predeclared types and functions, so known is false if it's declared elsewhere
+// e.g. in another file of the package or it's a selector on an imported package.
+func (cx *CurCtx) CalleeIsType() (yes bool, known bool) {
+	call := cx.enclosingCallArgs()
+	if call == nil {
+		return false, false
+	}
+	switch x := call.Fun.(type) {
+	case *ast.FuncLit:
+		return false, true
+	case *ast.Ident:
+		return cx.identIsType(x.Name)
+	}
+	if isTypeExpr(call.Fun) {
+		return true, true
+	}
+	return false, false
+}
+
+// identIsType reports whether the identifier name refers to a type, and whether that's known
+func (cx *CurCtx) identIsType(name string) (yes bool, known bool) {
+	decls := cx.localDecls()
+	for i := len(decls) - 1; i >= 0; i-- {
+		if d := decls[i]; d.Name.Name == name {
+			return d.Tok == token.TYPE, true
+		}
+	}
+	if cx.AstFile != nil {
+		for _, d := range cx.AstFile.Decls {
+			switch x := d.(type) {
+			case *ast.FuncDecl:
+				if x.Recv == nil && x.Name != nil && x.Name.Name == name {
+					return false, true
+				}
+			case *ast.GenDecl:
+				for _, d := range genDeclDecls(x) {
+					if d.Name.Name == name {
+						return d.Tok == token.TYPE, true
+					}
+				}
+			}
+		}
+	}
+	switch {
+	case predeclaredTypes[name]:
+		return true, true
+	case predeclaredFuncs[name]:
+		return false, true
+	}
+	return false, false
+}
+
+// RequiresConstExpr returns true if the cursor is in a position that requires a constant expression
+// i.e. the value of a const spec e.g. `const X = ‸` or the length of an array type e.g. `[‸]T`
+func (cx *CurCtx) RequiresConstExpr() bool {
+	if cx.inArrayLen() {
+		return true
+	}
+	var vs *ast.ValueSpec
+	if !cx.Scope.Is(ConstScope) || !cx.Set(&vs) || len(vs.Names) == 0 {
+		return false
+	}
+	var lhs ast.Node = vs.Names[len(vs.Names)-1]
+	if vs.Type != nil {
+		lhs = vs.Type
+	}
+	return cx.TokenPos > lhs.End()
+}
+
+// inArrayLen returns true if the cursor is between the brackets of an array (or slice) type
+func (cx *CurCtx) inArrayLen() bool {
+	for i := len(cx.Nodes) - 1; i >= 0; i-- {
+		switch x := cx.Nodes[i].(type) {
+		case *ast.ArrayType:
+			if ts, ok := cx.Nodes[i-1].(*ast.TypeSpec); ok && ts.Name != nil && ts.Name.End() == x.Lbrack {
+				// `type T[P ‸]` is parsed as an array type until the constraint is typed
+				return false
+			}
+			if x.Lbrack < cx.TokenPos && (x.Elt == nil || cx.TokenPos < x.Elt.Pos()) {
+				return true
+			}
+		case *ast.CompositeLit, *ast.FuncLit, ast.Stmt:
+			return false
+		}
+	}
+	return false
+}
+
+// PanicRecoverContext returns the name of the builtin `panic` or `recover`
+// iff the cursor is inside the parens of a call to it e.g. `panic(‸)`.
+//
+// Calls to user-declared functions with the same name are ignored,
+// and `recover` is only reported inside a defer statement, where it's useful.
+func (cx *CurCtx) PanicRecoverContext() (fn string, ok bool) {
+	call := cx.enclosingCallArgs()
+	if call == nil {
+		return "", false
+	}
+	id, _ := call.Fun.(*ast.Ident)
+	if id == nil || (id.Name != "panic" && id.Name != "recover") || cx.isDeclared(id.Name) {
+		return "", false
+	}
+	if id.Name == "recover" && !cx.Contains((*ast.DeferStmt)(nil)) {
+		return "", false
+	}
+	return id.Name, true
+}
+
+// EnclosingCall returns the innermost call whose parens enclose the cursor,
+// and the index of the argument the cursor is in.
+//
+// argIndex is computed from the source so it's robust while the call is being typed:
+// the cursor after a comma is in the next argument e.g. 1 in `f(a,‸)` or `f(a, ‸)`,
+// operands not separated by a comma are counted as separate arguments e.g. 1 in `f(a b‸)`,
+// and commas in comments, strings and nested brackets are ignored.
+func (cx *CurCtx) EnclosingCall() (call *ast.CallExpr, argIndex int, ok bool) {
+	call = cx.enclosingCallArgs()
+	if call == nil {
+		return nil, 0, false
+	}
+	start := cx.TokenFile.Offset(call.Lparen) + 1
+	end := cx.srcPos
+	if call.Rparen.IsValid() {
+		if rp := cx.TokenFile.Offset(call.Rparen); end > rp {
+			end = rp
+		}
+	}
+	if end < start {
+		return call, 0, true
+	}
+	return call, callArgIndex(cx.Src[start:end]), true
+}
+
+// AppendElemType returns the element type of the slice passed to the builtin append call enclosing the cursor
+// e.g. `Item` in `append(items, ‸)` where `var items []Item`, for any argument after the first.
+// If the cursor is in the spread argument e.g. `append(items, ‸...)`, the slice type `[]Item` is returned instead.
+//
+// The slice must be a variable whose type is declared in the file, or a trivially typed expression.
+func (cx *CurCtx) AppendElemType() (ast.Expr, bool) {
+	call, i, ok := cx.EnclosingCall()
+	if !ok || i < 1 || len(call.Args) == 0 {
+		return nil, false
+	}
+	if id, ok := call.Fun.(*ast.Ident); !ok || id.Name != "append" || cx.isDeclared(id.Name) {
+		return nil, false
+	}
+	typ := cx.valueType(call.Args[0])
+	at, _ := cx.underlyingType(typ).(*ast.ArrayType)
+	if at == nil || at.Len != nil {
+		return nil, false
+	}
+	if call.Ellipsis.IsValid() && i == len(call.Args)-1 {
+		return typ, true
+	}
+	return at.Elt, true
+}
+
+// callArgIndex returns the index of the argument at the end of src, which starts after the call's `(`
+func callArgIndex(src []byte) int {
+	var sc scanner.Scanner
+	sc.Init(token.NewFileSet().AddFile("", -1, len(src)), src, nil, 0)
+	index, depth := 0, 0
+	prev := token.ILLEGAL
+	for {
+		_, tok, lit := sc.Scan()
+		switch {
+		case tok == token.EOF:
+			return index
+		case tok == token.SEMICOLON && lit == "\n":
+			// automatically inserted at newlines
+			continue
+		case tok == token.LPAREN || tok == token.LBRACK || tok == token.LBRACE:
+			depth++
+		case tok == token.RPAREN || tok == token.RBRACK || tok == token.RBRACE:
+			depth--
+		case depth != 0:
+		case tok == token.COMMA:
+			index++
+		case tok.IsLiteral():
+			// a comma is missing e.g. `f(a b‸)`
+			// closing brackets are ignored because they may end a type e.g. `[]int`
+			if prev.IsLiteral() {
+				index++
+			}
+		}
+		prev = tok
+	}
+}
+
+// enclosingCallArgs returns the innermost call whose argument list encloses the cursor.
+// It returns nil if a composite literal, func literal or statement is reached first.
+func (cx *CurCtx) enclosingCallArgs() *ast.CallExpr {
+	for i := len(cx.Nodes) - 1; i >= 0; i-- {
+		switch x := cx.Nodes[i].(type) {
+		case *ast.CallExpr:
+			if x.Lparen.IsValid() && cx.inBrackets(x.Lparen, x.Rparen) {
+				return x
+			}
+		case *ast.CompositeLit, *ast.FuncLit, ast.Stmt:
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package cursor
+
+import (
+	"testing"
+)
+
+func TestConversionContext(t *testing.T) {
+	cases := []struct {
+		src string
+		typ string
+	}{
+		{"package p\nfunc f() {\n\tx := []byte(‸)\n}\n", "[]byte"},
+		{"package p\nfunc f() {\n\tx := []rune(s‸)\n}\n", "[]rune"},
+		{"package p\nfunc f() {\n\tx := map[string]int(‸)\n}\n", "map[string]int"},
+		{"package p\nfunc f() {\n\tx := [4]byte(‸)\n}\n", "[4]byte"},
+		{"package p\nfunc f() {\n\tx := (chan int)(‸)\n}\n", "(chan int)"},
+		{"package p\nfunc f() {\n\tx := string(‸)\n}\n", "string"},
+		{"package p\nfunc f() {\n\tx := []byte(fo‸o(s))\n}\n", "[]byte"},
+		{"package p\nfunc f() {\n\tx := []byte(‸\n}\n", "[]byte"},
+		{"package p\nfunc f() {\n\tx := string(s‸\n}\n", "string"},
+		{"package p\nfunc f() {\n\tx := foo(‸\n}\n", ""},
+		{"package p\nfunc f() {\n\tx := []T{‸}\n}\n", ""},
+		{"package p\nfunc f() {\n\tx := []byte(foo(‸))\n}\n", ""},
+		{"package p\nfunc f() {\n\tx := foo(‸)\n}\n", ""},
+		{"package p\nfunc f() {\n\tx := [‸]byte(s)\n}\n", ""},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		typ, ok := cx.ConversionContext()
+		got := ""
+		if ok {
+			got, _ = cx.Print(typ)
+		}
+		if got != c.typ {
+			t.Errorf("ConversionContext() = `%s`, want `%s` in %q", got, c.typ, c.src)
+		}
+	}
+}
+
+func TestAppendElemType(t *testing.T) {
+	decls := "package p\ntype Item struct{}\ntype Items []*Item\nvar all []Item\n"
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"var items []Item\n\titems = append(items, ‸)", "Item"},
+		{"var items []Item\n\titems = append(items, Item{}, ‸)", "Item"},
+		{"all = append(all, ‸)", "Item"},
+		{"var items Items\n\titems = append(items, ‸)", "*Item"},
+		{"items := []string{}\n\titems = append(items, ‸)", "string"},
+		{"var items []Item\n\titems = append(items, all‸...)", "[]Item"},
+		{"var items []Item\n\titems = append(‸)", ""},
+		{"var items [3]Item\n\tx := append(items, ‸)", ""},
+		{"x := append(pkg.Items, ‸)", ""},
+		{"append := func(l []Item, v ...Item) {}\n\tappend(all, ‸)", ""},
+	}
+	for _, c := range cases {
+		src := decls + "func f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		typ, ok := cx.AppendElemType()
+		got := ""
+		if typ != nil {
+			got, _ = cx.Print(typ)
+		}
+		if got != c.want || ok != (c.want != "") {
+			t.Errorf("AppendElemType() = (`%s`, %v), want (`%s`, %v) in %q", got, ok, c.want, c.want != "", c.src)
+		}
+	}
+}
+
+func TestTypeUseKind(t *testing.T) {
+	cases := []struct {
+		src  string
+		kind TypeUseKind
+		ok   bool
+	}{
+		{"x := T{‸}", CompositeLitTypeUse, true},
+		{"x := T{‸", CompositeLitTypeUse, true},
+		{"x := T{A: ‸", CompositeLitTypeUse, true},
+		{"x := T(‸)", ConversionTypeUse, true},
+		{"x := T(‸", ConversionTypeUse, true},
+		{"x := []byte(‸", ConversionTypeUse, true},
+		{"x := T{A: int(‸)}", ConversionTypeUse, true},
+		{"x := pkg.T(‸)", UnknownTypeUse, true},
+		{"x := g(‸)", UnknownTypeUse, false},
+		{"x := T{A: g(‸)}", UnknownTypeUse, false},
+		{"x := T‸", UnknownTypeUse, false},
+	}
+	for _, c := range cases {
+		src := "package p\ntype T struct{ A int }\nfunc g(int) int { return 0 }\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		kind, ok := cx.TypeUseKind()
+		if kind != c.kind || ok != c.ok {
+			t.Errorf("TypeUseKind() = (%v, %v), want (%v, %v) in %q", kind, ok, c.kind, c.ok, c.src)
+		}
+		if got, want := cx.Scope.Is(CompositeLitScope), c.kind == CompositeLitTypeUse; got != want {
+			t.Errorf("Scope.Is(CompositeLitScope) = %v, want %v in %q", got, want, c.src)
+		}
+	}
+}
+
+func TestEnclosingCallArgIndex(t *testing.T) {
+	cases := []struct {
+		src   string
+		index int
+	}{
+		{"f(‸)", 0},
+		{"f(a‸)", 0},
+		{"f(a ‸)", 0},
+		{"f(a,‸)", 1},
+		{"f(a, ‸)", 1},
+		{"f(a, b‸)", 1},
+		{"f(a, b, ‸)", 2},
+		{"f(a,\n\t\t‸)", 1},
+		{"f(a,\n\t\tb,\n\t\t‸\n\t)", 2},
+		{"f(a b‸)", 1},
+		{"f(a /* , */ ‸)", 0},
+		{"f(a /* , */, ‸)", 1},
+		{"f(a, // x, y\n\t\t‸)", 1},
+		{"f(\"a,b\", ‸)", 1},
+		{"f(g(a, b), ‸)", 1},
+		{"f([]int{1, 2}, m[k], ‸)", 2},
+		{"f(func(a, b int) {}, ‸)", 1},
+		{"f(a, g(b, ‸))", 1},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		_, index, ok := cx.EnclosingCall()
+		if !ok || index != c.index {
+			t.Errorf("EnclosingCall() = (%d, %v), want (%d, true) in %q", index, ok, c.index, c.src)
+		}
+	}
+}
+
+func TestCalleeIsType(t *testing.T) {
+	cases := []struct {
+		src   string
+		yes   bool
+		known bool
+	}{
+		{"x := T(‸)", true, true},
+		{"x := Fn(‸)", true, true},
+		{"x := g(‸)", false, true},
+		{"x := int(‸)", true, true},
+		{"x := string(s‸)", true, true},
+		{"x := []byte(‸)", true, true},
+		{"x := len(‸)", false, true},
+		{"x := append(‸)", false, true},
+		{"x := func() int { return 0 }(‸)", false, true},
+		{"type L int\n\tx := L(‸)", true, true},
+		{"int := g\n\tx := int(‸)", false, true},
+		{"x := unknown(‸)", false, false},
+		{"x := pkg.F(‸)", false, false},
+		{"x := g‸", false, false},
+	}
+	for _, c := range cases {
+		src := "package p\ntype T struct{}\ntype Fn func()\nfunc g() int { return 0 }\nfunc f(s string) {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		yes, known := cx.CalleeIsType()
+		if yes != c.yes || known != c.known {
+			t.Errorf("CalleeIsType() = (%v, %v), want (%v, %v) in %q", yes, known, c.yes, c.known, c.src)
+		}
+	}
+}
+
+func TestRequiresConstExpr(t *testing.T) {
+	cases := []struct {
+		src  string
+		want bool
+	}{
+		{"var a [‸]int", true},
+		{"var a [N + ‸]int", true},
+		{"func f() {\n\tx := [‸]byte{}\n}", true},
+		{"var a [4]in‸t", false},
+		{"const X = ‸", true},
+		{"const X int = 1 + ‸", true},
+		{"const (\n\tA = iota\n\tB = ‸\n)", true},
+		{"const X‸ = 1", false},
+		{"var X = ‸", false},
+		{"func f(x int) {\n\tswitch x {\n\tcase y‸:\n\t}\n}", false},
+		{"type Stack[‸]", false},
+		{"type Stack[T ‸]", false},
+		{"type Arr [‸]int", true},
+	}
+	for _, c := range cases {
+		src := "package p\n" + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		if got := cx.RequiresConstExpr(); got != c.want {
+			t.Errorf("RequiresConstExpr() = %v, want %v in %q", got, c.want, c.src)
+		}
+	}
+}
+
+func TestPanicRecoverContext(t *testing.T) {
+	cases := []struct {
+		src  string
+		decl string
+		fn   string
+	}{
+		{"panic(‸)", "", "panic"},
+		{"panic(‸", "", "panic"},
+		{"panic(fmt.Sprint(x), ‸)", "", "panic"},
+		{"panic(g(‸))", "", ""},
+		{"defer func() {\n\t\tif e := recover(‸); e != nil {\n\t\t}\n\t}()", "", "recover"},
+		{"defer recover(‸)", "", "recover"},
+		{"x := recover(‸)", "", ""},
+		{"panic(‸)", "func panic(v int) {}\n", ""},
+		{"panic‸()", "", ""},
+	}
+	for _, c := range cases {
+		src := "package p\n" + c.decl + "func f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		fn, ok := cx.PanicRecoverContext()
+		if fn != c.fn || ok != (c.fn != "") {
+			t.Errorf("PanicRecoverContext() = (%q, %v), want (%q, %v) in %q", fn, ok, c.fn, c.fn != "", c.src)
+		}
+	}
+}
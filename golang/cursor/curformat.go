@@ -0,0 +1,136 @@
+package cursor
+
+import (
+	"go/ast"
+	"strings"
+	"sync"
+)
+
+var (
+	printfFuncs = struct {
+		sync.RWMutex
+		m map[string]int
+	}{m: map[string]int{
+		"fmt.Errorf":  0,
+		"fmt.Printf":  0,
+		"fmt.Sprintf": 0,
+		"fmt.Fprintf": 1,
+		"fmt.Appendf": 1,
+		"fmt.Scanf":   0,
+		"fmt.Sscanf":  1,
+		"fmt.Fscanf":  1,
+		"log.Printf":  0,
+		"log.Fatalf":  0,
+		"log.Panicf":  0,
+		"Errorf":      0,
+		"Fatalf":      0,
+		"Logf":        0,
+		"Panicf":      0,
+		"Printf":      0,
+		"Skipf":       0,
+		"Sprintf":     0,
+	}}
+)
+
+// RegisterPrintfFunc registers the printf-like function name whose format string
+// is the argument at index formatArgIndex.
+//
+// If name contains a dot e.g. `log.Infof`, it must match the callee exactly,
+// otherwise it matches any function or method with that name e.g. `Infof`
+// matches `logger.Infof(...)` and `zap.S().Infof(...)`.
+//
+// Defaults are registered for the fmt and log packages,
+// and the common methods of testing.T and log.Logger.
+func RegisterPrintfFunc(name string, formatArgIndex int) {
+	printfFuncs.Lock()
+	defer printfFuncs.Unlock()
+
+	printfFuncs.m[name] = formatArgIndex
+}
+
+// FormatVerbContext returns the name of the printf-like function iff the cursor is in its format string.
+// verb is the partial verb immediately before the cursor e.g. `%-5` in `"x=%-5‸"`, or empty.
+//
+// If ok is true, the cursor is in FormatStringScope.
+func (cx *CurCtx) FormatVerbContext() (fn string, verb string, ok bool) {
+	fn, ok = cx.printfFuncCtx()
+	if !ok {
+		return "", "", false
+	}
+	lit := cx.BasicLit
+	start := cx.TokenFile.Offset(lit.Pos()) + 1
+	verb = partialVerb(string(cx.Src[start:cx.srcPos]))
+	return fn, verb, true
+}
+
+// partialVerb returns the incomplete verb at the end of the format string s
+func partialVerb(s string) string {
+	verb := ""
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '%' && verb == "%":
+			// `%%` is an escaped percent
+			verb = ""
+		case c == '%':
+			verb = "%"
+		case verb != "" && strings.IndexByte("+-# 0123456789.*[]", c) >= 0:
+			verb += string(c)
+		default:
+			verb = ""
+		}
+	}
+	return verb
+}
+
+// printfFuncCtx returns the name of the printf-like function whose format string encloses the cursor
+func (cx *CurCtx) printfFuncCtx() (fn string, ok bool) {
+	lit := cx.BasicLit
+	if lit == nil || !cx.Scope.Is(StringScope) || cx.ImportSpec != nil {
+		return "", false
+	}
+	if start := cx.TokenFile.Offset(lit.Pos()); cx.srcPos <= start || cx.srcPos > start+len(lit.Value) {
+		return "", false
+	}
+	call := cx.enclosingCallArgs()
+	if call == nil {
+		return "", false
+	}
+	argIdx := -1
+	for i, a := range call.Args {
+		if a == lit {
+			argIdx = i
+		}
+	}
+	if argIdx < 0 {
+		return "", false
+	}
+
+	name := ""
+	switch x := call.Fun.(type) {
+	case *ast.Ident:
+		name = x.Name
+	case *ast.SelectorExpr:
+		name = x.Sel.Name
+		if id, ok := x.X.(*ast.Ident); ok {
+			if i, ok := printfFuncIndex(id.Name + "." + name); ok {
+				if i == argIdx {
+					return id.Name + "." + name, true
+				}
+				return "", false
+			}
+		}
+	}
+	if i, ok := printfFuncIndex(name); ok && i == argIdx {
+		return name, true
+	}
+	return "", false
+}
+
+func printfFuncIndex(name string) (int, bool) {
+	printfFuncs.RLock()
+	defer printfFuncs.RUnlock()
+
+	i, ok := printfFuncs.m[name]
+	return i, ok
+}
@@ -0,0 +1,39 @@
+package cursor
+
+import (
+	"testing"
+)
+
+func TestFormatVerbContext(t *testing.T) {
+	RegisterPrintfFunc("Infof", 0)
+	RegisterPrintfFunc("klog.InfoDepthf", 1)
+
+	cases := []struct {
+		src  string
+		fn   string
+		verb string
+	}{
+		{"package p\nfunc f() {\n\tfmt.Printf(\"x=‸\", x)\n}\n", "fmt.Printf", ""},
+		{"package p\nfunc f() {\n\tfmt.Printf(\"x=%‸\", x)\n}\n", "fmt.Printf", "%"},
+		{"package p\nfunc f() {\n\tfmt.Printf(\"x=%-5‸\", x)\n}\n", "fmt.Printf", "%-5"},
+		{"package p\nfunc f() {\n\tfmt.Printf(\"x=%%‸\", x)\n}\n", "fmt.Printf", ""},
+		{"package p\nfunc f() {\n\tfmt.Printf(\"x=%d ‸\", x)\n}\n", "fmt.Printf", ""},
+		{"package p\nfunc f() {\n\tfmt.Fprintf(w, \"x=%‸\", x)\n}\n", "fmt.Fprintf", "%"},
+		{"package p\nfunc f() {\n\tt.Errorf(\"x=%‸\", x)\n}\n", "Errorf", "%"},
+		{"package p\nfunc f() {\n\tzap.S().Infof(\"x=%‸\", x)\n}\n", "Infof", "%"},
+		{"package p\nfunc f() {\n\tklog.InfoDepthf(1, \"x=%‸\", x)\n}\n", "klog.InfoDepthf", "%"},
+		{"package p\nfunc f() {\n\tfmt.Fprintf(\"x=%‸\", x)\n}\n", "", ""},
+		{"package p\nfunc f() {\n\tfmt.Println(\"x=%‸\", x)\n}\n", "", ""},
+		{"package p\nfunc f() {\n\tfmt.Printf(\"%v\", \"x=%‸\")\n}\n", "", ""},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		fn, verb, ok := cx.FormatVerbContext()
+		if fn != c.fn || verb != c.verb || ok != (c.fn != "") {
+			t.Errorf("FormatVerbContext() = (%q, %q, %v), want (%q, %q, %v) in %q", fn, verb, ok, c.fn, c.verb, c.fn != "", c.src)
+		}
+		if ok != cx.Scope.Is(FormatStringScope) {
+			t.Errorf("Scope.Is(FormatStringScope) = %v, want %v in %q", cx.Scope.Is(FormatStringScope), ok, c.src)
+		}
+	}
+}
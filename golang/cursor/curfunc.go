@@ -0,0 +1,710 @@
+package cursor
+
+import (
+	"go/ast"
+	"go/token"
+	"margo.sh/golang/goutil"
+	"strconv"
+)
+
+// Param describes a single function parameter or result
+type Param struct {
+	// Name is the name of the param or empty if it's unnamed
+	Name string
+
+	// Type is the declared type of the param.
+	// For variadic params, it's the element type i.e. `int` in `...int`
+	Type ast.Expr
+
+	// Variadic is true if the param is declared as `...T`
+	Variadic bool
+}
+
+// Params returns the params of the FuncDecl or FuncLit enclosing the cursor.
+// Grouped params e.g. `a, b int` are expanded into a Param for each name.
+func (cx *CurCtx) Params() []Param {
+	typ, _ := funcTypeBody(cx.enclosingFunc())
+	if typ == nil {
+		return nil
+	}
+	return fieldListParams(typ.Params)
+}
+
+// ResultTypes returns the result types of the FuncDecl or FuncLit enclosing the cursor, in order.
+// Grouped results e.g. `(a, b int)` are expanded into a type for each name.
+// If the function has no results, the returned slice is empty and ok is true.
+func (cx *CurCtx) ResultTypes() ([]ast.Expr, bool) {
+	typ, _ := funcTypeBody(cx.enclosingFunc())
+	if typ == nil {
+		return nil, false
+	}
+	l := []ast.Expr{}
+	for _, p := range fieldListParams(typ.Results) {
+		l = append(l, p.Type)
+	}
+	return l, true
+}
+
+// FuncSignatureText returns the signature of the FuncDecl or FuncLit enclosing the cursor
+// e.g. `func (t *T) Name[P any](a, b int, s ...string) (int, error)`.
+// The name and receiver are omitted for func literals.
+func (cx *CurCtx) FuncSignatureText() (string, bool) {
+	var n ast.Node
+	switch x := cx.enclosingFunc().(type) {
+	case *ast.FuncDecl:
+		n = &ast.FuncDecl{Recv: x.Recv, Name: x.Name, Type: x.Type}
+	case *ast.FuncLit:
+		n = x.Type
+	default:
+		return "", false
+	}
+	s, err := cx.Print(n)
+	return s, err == nil
+}
+
+// ReturnExprIndex returns the index of the result expression the cursor is in, in the enclosing return statement
+// e.g. 1 in `return x, ‸`.
+//
+// The index is computed from the source, as for EnclosingCall, so it's robust to trailing commas
+// and incomplete expressions.
+func (cx *CurCtx) ReturnExprIndex() (index int, ok bool) {
+	ret, _ := cx.enclosingReturn()
+	if ret == nil {
+		return 0, false
+	}
+	start := cx.TokenFile.Offset(ret.Return) + len(token.RETURN.String())
+	if cx.srcPos < start {
+		return 0, false
+	}
+	return callArgIndex(cx.Src[start:cx.srcPos]), true
+}
+
+// ReturnNilContext returns the result type of the function enclosing the cursor
+// iff the cursor is in a `return nil` statement and the function returns a single pointer, slice or map
+// e.g. `*T` in `func f() *T { return nil‸ }`, where it may be unclear whether the nil is intentional.
+// Result types declared in the file are resolved to their underlying type, but the declared type is returned.
+func (cx *CurCtx) ReturnNilContext() (resultType ast.Expr, ok bool) {
+	ret, _ := cx.enclosingReturn()
+	if ret == nil || len(ret.Results) != 1 {
+		return nil, false
+	}
+	if id, _ := ret.Results[0].(*ast.Ident); id == nil || id.Name != "nil" || cx.isDeclared("nil") {
+		return nil, false
+	}
+	if i, ok := cx.ReturnExprIndex(); !ok || i != 0 {
+		return nil, false
+	}
+	results, _ := cx.ResultTypes()
+	if len(results) != 1 {
+		return nil, false
+	}
+	switch x := cx.underlyingType(results[0]).(type) {
+	case *ast.StarExpr, *ast.MapType:
+		return results[0], true
+	case *ast.ArrayType:
+		if x.Len == nil {
+			return results[0], true
+		}
+	}
+	return nil, false
+}
+
+// ClosureResultType returns the type of the result at index of the func literal whose body encloses the cursor
+// e.g. `int` for index 0 in `slices.SortFunc(xs, func(a, b T) int { return ‸ })`.
+// Grouped results e.g. `(a, b int)` are expanded, as for Params.
+//
+// ok is false if the innermost function enclosing the cursor is a FuncDecl, not a func literal,
+// or it has no result at index.
+func (cx *CurCtx) ClosureResultType(index int) (ast.Expr, bool) {
+	fl, _ := cx.enclosingFunc().(*ast.FuncLit)
+	if fl == nil || fl.Body == nil || !goutil.NodeEnclosesPos(fl.Body, cx.TokenPos) {
+		return nil, false
+	}
+	results := fieldListParams(fl.Type.Results)
+	if index < 0 || index >= len(results) {
+		return nil, false
+	}
+	return results[index].Type, true
+}
+
+// EnclosingFuncExported returns whether the FuncDecl enclosing the cursor is exported.
+// Methods are only considered exported if their receiver's base type is also exported
+// e.g. `func (t *T) Name()` but not `func (t *t) Name()`.
+// Func literals are skipped in favour of the FuncDecl they're declared in.
+func (cx *CurCtx) EnclosingFuncExported() (exported bool, ok bool) {
+	var fd *ast.FuncDecl
+	if !cx.Set(&fd) || fd.Name == nil {
+		return false, false
+	}
+	if !fd.Name.IsExported() {
+		return false, true
+	}
+	if fd.Recv == nil || len(fd.Recv.List) == 0 {
+		return true, true
+	}
+	typ := fd.Recv.List[0].Type
+	if x, ok := typ.(*ast.StarExpr); ok {
+		typ = x.X
+	}
+	id := typeNameIdent(typ)
+	return id != nil && id.IsExported(), true
+}
+
+// DocStubInputs returns the name, param names and result names of the FuncDecl enclosing the cursor,
+// or documented by the doc comment the cursor is in, for generating a doc comment.
+// Unnamed and blank (`_`) params and results are omitted.
+func (cx *CurCtx) DocStubInputs() (name string, params []string, results []string, ok bool) {
+	fd := cx.docFuncDecl()
+	if fd == nil {
+		return "", nil, nil, false
+	}
+	names := func(fl *ast.FieldList) []string {
+		l := []string{}
+		for _, p := range fieldListParams(fl) {
+			if p.Name != "" && p.Name != "_" {
+				l = append(l, p.Name)
+			}
+		}
+		return l
+	}
+	return fd.Name.Name, names(fd.Type.Params), names(fd.Type.Results), true
+}
+
+// EnclosingFuncHasDoc returns whether the FuncDecl enclosing the cursor, or documented by the doc comment
+// the cursor is in, has a non-empty doc comment. ok is false if there is no such FuncDecl.
+func (cx *CurCtx) EnclosingFuncHasDoc() (hasDoc bool, ok bool) {
+	fd := cx.docFuncDecl()
+	if fd == nil {
+		return false, false
+	}
+	return fd.Doc.Text() != "", true
+}
+
+// docFuncDecl returns the FuncDecl documented by the doc comment the cursor is in,
+// or the FuncDecl enclosing the cursor
+func (cx *CurCtx) docFuncDecl() *ast.FuncDecl {
+	var fd *ast.FuncDecl
+	if cx.Doc != nil {
+		fd, _ = cx.Doc.Node.(*ast.FuncDecl)
+	}
+	if fd == nil {
+		cx.Set(&fd)
+	}
+	return fd
+}
+
+// CallArgType returns the type of the param corresponding to the argument the cursor is in
+// e.g. `int` in `f(x, ‸)` where `func f(s string, n int)`.
+// For variadic params, typ is the element type and variadic is true.
+//
+// The callee must be a function declared in the file, a local variable of func type,
+// or a method on a variable whose type is declared in the file.
+func (cx *CurCtx) CallArgType() (typ ast.Expr, variadic bool, ok bool) {
+	call, i, ok := cx.EnclosingCall()
+	if !ok {
+		return nil, false, false
+	}
+	p, ok := funcTypeParam(cx.calleeFuncType(call.Fun), i)
+	if !ok {
+		return nil, false, false
+	}
+	return p.Type, p.Variadic, true
+}
+
+// ExpectedCallbackSignature returns the func type expected by the call for the func literal the cursor is in
+// e.g. `func(a, b T) int` in `slices.SortFunc(xs, func(‸) {})` where the param is declared as `cmp func(a, b T) int`.
+// Named func types e.g. `type Handler func(w Writer)` are resolved to their underlying func type.
+//
+// The callee is resolved as for CallArgType, so ok is false if its signature isn't in the file.
+func (cx *CurCtx) ExpectedCallbackSignature() (*ast.FuncType, bool) {
+	for i := len(cx.Nodes) - 1; i >= 1; i-- {
+		fl, _ := cx.Nodes[i].(*ast.FuncLit)
+		if fl == nil {
+			continue
+		}
+		call, _ := cx.Nodes[i-1].(*ast.CallExpr)
+		if call == nil {
+			return nil, false
+		}
+		for j, a := range call.Args {
+			if a != fl {
+				continue
+			}
+			p, ok := funcTypeParam(cx.calleeFuncType(call.Fun), j)
+			if !ok {
+				return nil, false
+			}
+			ft, _ := cx.underlyingType(p.Type).(*ast.FuncType)
+			return ft, ft != nil
+		}
+		return nil, false
+	}
+	return nil, false
+}
+
+// funcTypeParam returns the param of ft corresponding to the argument at index i.
+// Arguments after the last param correspond to it if it's variadic.
+func funcTypeParam(ft *ast.FuncType, i int) (Param, bool) {
+	if ft == nil {
+		return Param{}, false
+	}
+	params := fieldListParams(ft.Params)
+	n := len(params)
+	switch {
+	case i < n:
+	case n != 0 && params[n-1].Variadic:
+		i = n - 1
+	default:
+		return Param{}, false
+	}
+	return params[i], true
+}
+
+// calleeFuncType returns the signature of the function fun
+func (cx *CurCtx) calleeFuncType(fun ast.Expr) *ast.FuncType {
+	switch x := fun.(type) {
+	case *ast.ParenExpr:
+		return cx.calleeFuncType(x.X)
+	case *ast.FuncLit:
+		return x.Type
+	case *ast.Ident:
+		if typ, ok := cx.varType(x.Name); ok {
+			ft, _ := cx.underlyingType(typ).(*ast.FuncType)
+			return ft
+		}
+		for _, d := range cx.localDecls() {
+			if d.Name.Name == x.Name {
+				// shadowed
+				return nil
+			}
+		}
+		if fd := cx.funcDecl("", x.Name); fd != nil {
+			return fd.Type
+		}
+	case *ast.SelectorExpr:
+		id, _ := x.X.(*ast.Ident)
+		if id == nil {
+			return nil
+		}
+		typ, ok := cx.varType(id.Name)
+		if !ok {
+			return nil
+		}
+		if x, ok := typ.(*ast.StarExpr); ok {
+			typ = x.X
+		}
+		if tn := typeNameIdent(typ); tn != nil {
+			if fd := cx.funcDecl(tn.Name, x.Sel.Name); fd != nil {
+				return fd.Type
+			}
+		}
+	}
+	return nil
+}
+
+// funcDecl returns the declaration of the function name declared in the file,
+// or the method name on the type recv if recv is not empty
+func (cx *CurCtx) funcDecl(recv, name string) *ast.FuncDecl {
+	if cx.AstFile == nil {
+		return nil
+	}
+	for _, d := range cx.AstFile.Decls {
+		fd, ok := d.(*ast.FuncDecl)
+		if !ok || fd.Name.Name != name {
+			continue
+		}
+		if recv == "" && fd.Recv == nil {
+			return fd
+		}
+		if recv == "" || fd.Recv == nil || len(fd.Recv.List) == 0 {
+			continue
+		}
+		typ := fd.Recv.List[0].Type
+		if x, ok := typ.(*ast.StarExpr); ok {
+			typ = x.X
+		}
+		if id := typeNameIdent(typ); id != nil && id.Name == recv {
+			return fd
+		}
+	}
+	return nil
+}
+
+// HasNamedResults returns true if the results of the FuncDecl (including methods) or FuncLit
+// enclosing the cursor are named e.g. `func f() (n int, err error)`, so a naked `return` is allowed.
+func (cx *CurCtx) HasNamedResults() bool {
+	typ, _ := funcTypeBody(cx.enclosingFunc())
+	return typ != nil && namedResults(typ) != nil
+}
+
+// ContextParam returns the name of the first parameter of the function enclosing the cursor
+// iff its type is `context.Context` e.g. `ctx` in `func f(ctx context.Context, ...) { ‸ }`.
+//
+// The type is matched syntactically against the file's imports, so aliased imports
+// e.g. `import stdctx "context"` are handled. Func literals that don't take a context
+// see the parameter of the enclosing function, so they're skipped.
+func (cx *CurCtx) ContextParam() (name string, ok bool) {
+	for i := len(cx.Nodes) - 1; i >= 0; i-- {
+		typ, body := funcTypeBody(cx.Nodes[i])
+		if typ == nil || body == nil || !goutil.NodeEnclosesPos(body, cx.TokenPos) {
+			continue
+		}
+		if typ.Params == nil || len(typ.Params.List) == 0 {
+			continue
+		}
+		f := typ.Params.List[0]
+		if len(f.Names) == 0 || f.Names[0].Name == "_" || !cx.isContextType(f.Type) {
+			continue
+		}
+		return f.Names[0].Name, true
+	}
+	return "", false
+}
+
+// isContextType returns true if typ refers to `context.Context`, via the file's imports of package context
+func (cx *CurCtx) isContextType(typ ast.Expr) bool {
+	if cx.AstFile == nil {
+		return false
+	}
+	pkg, sel := "", ""
+	switch x := typ.(type) {
+	case *ast.SelectorExpr:
+		id, _ := x.X.(*ast.Ident)
+		if id == nil {
+			return false
+		}
+		pkg, sel = id.Name, x.Sel.Name
+	case *ast.Ident:
+		pkg, sel = ".", x.Name
+	}
+	if sel != "Context" {
+		return false
+	}
+	for _, spec := range cx.AstFile.Imports {
+		if p, _ := strconv.Unquote(spec.Path.Value); p == "context" && importSpecName(spec) == pkg {
+			return true
+		}
+	}
+	return false
+}
+
+// CanBareReturn returns true if a bare `return` is valid in the FuncDecl or FuncLit enclosing the cursor
+// i.e. it has no results, or its results are named, e.g. for gating guard-clause snippets like `if err != nil { return }`.
+func (cx *CurCtx) CanBareReturn() bool {
+	typ, _ := funcTypeBody(cx.enclosingFunc())
+	if typ == nil {
+		return false
+	}
+	return typ.Results == nil || len(typ.Results.List) == 0 || namedResults(typ) != nil
+}
+
+// UnassignedNamedResults returns the named results of the FuncDecl or FuncLit enclosing the cursor
+// that aren't assigned before the cursor e.g. `err` in `func f() (n int, err error) { n = 1; ‸ }`,
+// so a bare return at the cursor would return their zero value.
+// The blank identifier `_` is never included.
+//
+// Control flow is ignored: any assignment, increment or address-of `&` before the cursor counts,
+// even in a branch that might not be taken, or a closure.
+// A short variable declaration only counts if it's directly in the function body, because elsewhere it shadows the result.
+// ok is false if the function doesn't have named results, or the cursor isn't in its body.
+func (cx *CurCtx) UnassignedNamedResults() (names []string, ok bool) {
+	typ, body := funcTypeBody(cx.enclosingFunc())
+	if typ == nil || body == nil || !goutil.NodeEnclosesPos(body, cx.TokenPos) || cx.TokenPos <= body.Lbrace {
+		return nil, false
+	}
+	results := namedResults(typ)
+	if results == nil {
+		return nil, false
+	}
+
+	assigned := map[string]bool{}
+	assign := func(x ast.Expr) {
+		if id, ok := x.(*ast.Ident); ok {
+			assigned[id.Name] = true
+		}
+	}
+	topLevel := map[ast.Stmt]bool{}
+	for _, s := range body.List {
+		topLevel[s] = true
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		if n == nil || n.Pos() >= cx.TokenPos {
+			return false
+		}
+		// the statement at the cursor is still being typed
+		before := n.End() <= cx.TokenPos
+		switch x := n.(type) {
+		case *ast.AssignStmt:
+			if before && (x.Tok != token.DEFINE || topLevel[x]) {
+				for _, e := range x.Lhs {
+					assign(e)
+				}
+			}
+		case *ast.IncDecStmt:
+			if before {
+				assign(x.X)
+			}
+		case *ast.RangeStmt:
+			if x.Tok == token.ASSIGN {
+				assign(x.Key)
+				assign(x.Value)
+			}
+		case *ast.UnaryExpr:
+			if x.Op == token.AND {
+				assign(x.X)
+			}
+		}
+		return true
+	})
+
+	names = []string{}
+	for _, p := range results {
+		if p.Name != "_" && !assigned[p.Name] {
+			names = append(names, p.Name)
+		}
+	}
+	return names, true
+}
+
+// FuncBodyStart returns the offset, in cx.Src, just inside the opening brace of the body of the FuncDecl or FuncLit
+// enclosing the cursor, for inserting e.g. guard clauses.
+// If the body starts with comments, the offset is after the last comment before the first statement.
+func (cx *CurCtx) FuncBodyStart() (offset int, ok bool) {
+	_, body := funcTypeBody(cx.enclosingFunc())
+	if body == nil || !body.Lbrace.IsValid() {
+		return 0, false
+	}
+	pos := body.Lbrace + 1
+	end := body.Rbrace
+	if len(body.List) != 0 {
+		end = body.List[0].Pos()
+	}
+	if cx.AstFile != nil {
+		for _, cg := range cx.AstFile.Comments {
+			if cg.Pos() > body.Lbrace && cg.End() <= end && cg.End() > pos {
+				pos = cg.End()
+			}
+		}
+	}
+	return cx.TokenFile.Offset(pos), true
+}
+
+// InSingleReturnBody returns the return statement enclosing the cursor
+// iff it's the only statement in the body of the enclosing function e.g. `func() int { return ‸x }`.
+func (cx *CurCtx) InSingleReturnBody() (*ast.ReturnStmt, bool) {
+	ret, fn := cx.enclosingReturn()
+	if ret == nil {
+		return nil, false
+	}
+	_, body := funcTypeBody(fn)
+	if body == nil || len(body.List) != 1 || body.List[0] != ret {
+		return nil, false
+	}
+	return ret, true
+}
+
+// EnclosingFuncStats returns simple complexity metrics for the body of the FuncDecl or FuncLit enclosing the cursor:
+// the number of statements, the maximum depth of nested blocks and the number of return statements.
+//
+// Blocks themselves are not counted as statements, and the bodies of nested func literals are skipped.
+func (cx *CurCtx) EnclosingFuncStats() (statements int, nesting int, returns int, ok bool) {
+	_, body := funcTypeBody(cx.enclosingFunc())
+	if body == nil {
+		return 0, 0, 0, false
+	}
+	// the clauses of switch and select statements are nested blocks, but their bodies aren't,
+	// so they count as one level of nesting, like if and for statements
+	clauseBodies := map[*ast.BlockStmt]bool{}
+	var walk func(n ast.Node, depth int)
+	walk = func(n ast.Node, depth int) {
+		ast.Inspect(n, func(n ast.Node) bool {
+			switch x := n.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.SwitchStmt:
+				clauseBodies[x.Body] = true
+				statements++
+			case *ast.TypeSwitchStmt:
+				clauseBodies[x.Body] = true
+				statements++
+			case *ast.SelectStmt:
+				clauseBodies[x.Body] = true
+				statements++
+			case *ast.BlockStmt:
+				if clauseBodies[x] {
+					for _, s := range x.List {
+						walk(s, depth)
+					}
+					return false
+				}
+				if depth > nesting {
+					nesting = depth
+				}
+				for _, s := range x.List {
+					walk(s, depth+1)
+				}
+				return false
+			case *ast.CaseClause, *ast.CommClause:
+				if depth > nesting {
+					nesting = depth
+				}
+				for _, s := range blockStmts(x) {
+					walk(s, depth+1)
+				}
+				return false
+			case *ast.ReturnStmt:
+				returns++
+				statements++
+			case ast.Stmt:
+				statements++
+			}
+			return true
+		})
+	}
+	for _, s := range body.List {
+		walk(s, 1)
+	}
+	return statements, nesting, returns, true
+}
+
+// blockStmts returns the list of statements in the *ast.BlockStmt, *ast.CaseClause or *ast.CommClause n
+func blockStmts(n ast.Node) []ast.Stmt {
+	switch x := n.(type) {
+	case *ast.BlockStmt:
+		return x.List
+	case *ast.CaseClause:
+		return x.Body
+	case *ast.CommClause:
+		return x.Body
+	}
+	return nil
+}
+
+// ReturnArityMismatch returns the number of results the enclosing function declares (want)
+// and the number of values in the return statement enclosing the cursor (got).
+// ok is true iff the numbers don't match.
+//
+// Naked returns in functions with named results, and returns of a single call e.g. `return f()`
+// are never reported as mismatched.
+func (cx *CurCtx) ReturnArityMismatch() (want, got int, ok bool) {
+	ret, fn := cx.enclosingReturn()
+	if ret == nil {
+		return 0, 0, false
+	}
+	typ, _ := funcTypeBody(fn)
+	results := fieldListParams(typ.Results)
+	want, got = len(results), len(ret.Results)
+	switch {
+	case want == got:
+		return want, got, false
+	case got == 0 && want != 0 && results[0].Name != "":
+		return want, got, false
+	case got == 1 && want > 1:
+		if _, isCall := ret.Results[0].(*ast.CallExpr); isCall {
+			return want, got, false
+		}
+	}
+	return want, got, true
+}
+
+// OuterNamedResults returns the named results of the function enclosing the nearest deferred func literal
+// e.g. `err` in `func f() (err error) { defer func() { ‸ }() }`.
+// It returns nil if the cursor is not in a deferred func literal, or the outer function's results are unnamed.
+func (cx *CurCtx) OuterNamedResults() []Param {
+	deferred := false
+	for i := len(cx.Nodes) - 1; i >= 0; i-- {
+		switch x := cx.Nodes[i].(type) {
+		case *ast.FuncLit:
+			if deferred {
+				return namedResults(x.Type)
+			}
+			if i >= 2 && isDeferredCall(cx.Nodes[i-2], cx.Nodes[i-1], x) {
+				deferred = true
+			}
+		case *ast.FuncDecl:
+			if deferred {
+				return namedResults(x.Type)
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// isDeferredCall returns true if fn is the func called in the defer statement `defer fn()`
+func isDeferredCall(stmt, call, fn ast.Node) bool {
+	ds, _ := stmt.(*ast.DeferStmt)
+	return ds != nil && ds.Call == call && ds.Call.Fun == fn
+}
+
+// namedResults returns the results of typ if they're named
+func namedResults(typ *ast.FuncType) []Param {
+	l := fieldListParams(typ.Results)
+	if len(l) == 0 || l[0].Name == "" {
+		return nil
+	}
+	return l
+}
+
+// enclosingReturn returns the return statement enclosing the cursor and the function it returns from
+func (cx *CurCtx) enclosingReturn() (*ast.ReturnStmt, ast.Node) {
+	var ret *ast.ReturnStmt
+	for i := len(cx.Nodes) - 1; i >= 0; i-- {
+		switch x := cx.Nodes[i].(type) {
+		case *ast.ReturnStmt:
+			ret = x
+		case *ast.FuncDecl, *ast.FuncLit:
+			if ret == nil {
+				return nil, nil
+			}
+			return ret, x
+		}
+	}
+	return nil, nil
+}
+
+// enclosingFunc returns the innermost *ast.FuncDecl or *ast.FuncLit enclosing the cursor, or nil
+func (cx *CurCtx) enclosingFunc() ast.Node {
+	for i := len(cx.Nodes) - 1; i >= 0; i-- {
+		switch x := cx.Nodes[i].(type) {
+		case *ast.FuncDecl, *ast.FuncLit:
+			return x
+		}
+	}
+	return nil
+}
+
+// funcTypeBody returns the type and body of a *ast.FuncDecl or *ast.FuncLit
+func funcTypeBody(fn ast.Node) (*ast.FuncType, *ast.BlockStmt) {
+	switch x := fn.(type) {
+	case *ast.FuncDecl:
+		return x.Type, x.Body
+	case *ast.FuncLit:
+		return x.Type, x.Body
+	}
+	return nil, nil
+}
+
+// fieldListParams expands the fields in fl into a Param for each name
+func fieldListParams(fl *ast.FieldList) []Param {
+	if fl == nil {
+		return nil
+	}
+	l := []Param{}
+	for _, f := range fl.List {
+		p := Param{Type: f.Type}
+		if x, ok := f.Type.(*ast.Ellipsis); ok {
+			p.Type = x.Elt
+			p.Variadic = true
+		}
+		if len(f.Names) == 0 {
+			l = append(l, p)
+			continue
+		}
+		for _, id := range f.Names {
+			p.Name = id.Name
+			l = append(l, p)
+		}
+	}
+	return l
+}
@@ -0,0 +1,552 @@
+package cursor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParams(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"package p\nfunc f(a, b int, s ...string) {\n\t‸\n}\n", "a int, b int, s ...string"},
+		{"package p\nfunc (t T) f(x *T) {\n\t‸\n}\n", "x *T"},
+		{"package p\nfunc f(a int) {\n\tg := func(int, string) {\n\t\t‸\n\t}\n}\n", "int, string"},
+		{"package p\nfunc f() {\n\t‸\n}\n", ""},
+		{"package p\nvar x = ‸1\n", ""},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		got := ""
+		for i, p := range cx.Params() {
+			if i > 0 {
+				got += ", "
+			}
+			if p.Name != "" {
+				got += p.Name + " "
+			}
+			if p.Variadic {
+				got += "..."
+			}
+			s, _ := cx.Print(p.Type)
+			got += s
+		}
+		if got != c.want {
+			t.Errorf("Params() = `%s`, want `%s` in %q", got, c.want, c.src)
+		}
+	}
+}
+
+func TestResultTypes(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"package p\nfunc f() (int, error) {\n\treturn ‸\n}\n", "int, error"},
+		{"package p\nfunc f() (a, b string, err error) {\n\t‸\n}\n", "string, string, error"},
+		{"package p\nfunc f() *T {\n\t‸\n}\n", "*T"},
+		{"package p\nfunc f() {\n\t‸\n}\n", ""},
+		{"package p\nfunc f() error {\n\tg := func() (bool, int) {\n\t\treturn ‸\n\t}\n}\n", "bool, int"},
+		{"package p\nvar x = ‸1\n", "-"},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		types, ok := cx.ResultTypes()
+		got := "-"
+		if ok {
+			l := []string{}
+			for _, typ := range types {
+				s, _ := cx.Print(typ)
+				l = append(l, s)
+			}
+			got = strings.Join(l, ", ")
+		}
+		if got != c.want {
+			t.Errorf("ResultTypes() = `%s`, want `%s` in %q", got, c.want, c.src)
+		}
+	}
+}
+
+func TestFuncSignatureText(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{
+			"package p\nfunc (t *T) Name(a, b int,\n\ts ...string) (n int, err error) {\n\t‸\n}\n",
+			"func (t *T) Name(a, b int, s ...string) (n int, err error)",
+		},
+		{
+			"package p\nfunc Map[T, U any, N ~int | ~string](l []T, f func(T) U) []U {\n\t‸\n}\n",
+			"func Map[T, U any, N ~int | ~string](l []T, f func(T) U) []U",
+		},
+		{
+			"package p\nfunc f() {\n\tg := func(x int) error {\n\t\t‸\n\t}\n}\n",
+			"func(x int) error",
+		},
+		{"package p\nvar x = ‸1\n", ""},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		got, ok := cx.FuncSignatureText()
+		if got != c.want || ok != (c.want != "") {
+			t.Errorf("FuncSignatureText() = (`%s`, %v), want `%s` in %q", got, ok, c.want, c.src)
+		}
+	}
+}
+
+func TestOuterNamedResults(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"package p\nfunc f() (n int, err error) {\n\tdefer func() {\n\t\t‸\n\t}()\n}\n", "n, err"},
+		{"package p\nfunc f() (err error) {\n\tdefer func() {\n\t\tif true {\n\t\t\terr = wrap(e‸rr)\n\t\t}\n\t}()\n}\n", "err"},
+		{"package p\nfunc f() (err error) {\n\tg := func() (x int) {\n\t\tdefer func() {\n\t\t\t‸\n\t\t}()\n\t}\n}\n", "x"},
+		{"package p\nfunc f() (int, error) {\n\tdefer func() {\n\t\t‸\n\t}()\n}\n", ""},
+		{"package p\nfunc f() (err error) {\n\tgo func() {\n\t\t‸\n\t}()\n}\n", ""},
+		{"package p\nfunc f() (err error) {\n\t‸\n}\n", ""},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		got := ""
+		for i, p := range cx.OuterNamedResults() {
+			if i > 0 {
+				got += ", "
+			}
+			got += p.Name
+		}
+		if got != c.want {
+			t.Errorf("OuterNamedResults() = `%s`, want `%s` in %q", got, c.want, c.src)
+		}
+	}
+}
+
+func TestCanBareReturn(t *testing.T) {
+	cases := []struct {
+		src  string
+		want bool
+	}{
+		{"package p\nfunc f() {\n\t‸\n}\n", true},
+		{"package p\nfunc f() (n int, err error) {\n\t‸\n}\n", true},
+		{"package p\nfunc f() error {\n\t‸\n}\n", false},
+		{"package p\nfunc f() (int, error) {\n\t‸\n}\n", false},
+		{"package p\nfunc f() error {\n\tg := func() {\n\t\t‸\n\t}\n}\n", true},
+		{"package p\nfunc f() {\n\tg := func() bool {\n\t\t‸\n\t}\n}\n", false},
+		{"package p\nvar x = ‸1\n", false},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		if got := cx.CanBareReturn(); got != c.want {
+			t.Errorf("CanBareReturn() = %v, want %v in %q", got, c.want, c.src)
+		}
+	}
+}
+
+func TestFuncBodyStart(t *testing.T) {
+	cases := []struct {
+		src    string
+		before string
+	}{
+		{"func f(p *T) {\n\tx := 1\n\t‸\n}\n", "func f(p *T) {"},
+		{"func f() {‸}\n", "func f() {"},
+		{"func f() {\n\t// validate\n\t/* p */\n\tx := ‸1\n}\n", "func f() {\n\t// validate\n\t/* p */"},
+		{"func f() {\n\t// nothing yet\n\t‸\n}\n", "func f() {\n\t// nothing yet"},
+		{"func f() {\n\tx := 1 // one\n\t‸\n}\n", "func f() {"},
+		{"func f() {\n\tg := func() {\n\t\t‸\n\t}\n}\n", "func f() {\n\tg := func() {"},
+		{"var x = ‸1\n", ""},
+	}
+	for _, c := range cases {
+		src := "package p\n" + c.src
+		cx := newTestCurCtx(t, src)
+		offset, ok := cx.FuncBodyStart()
+		got := ""
+		if ok {
+			got = string(cx.Src[:offset])
+		}
+		if !strings.HasSuffix(got, c.before) || ok != (c.before != "") {
+			t.Errorf("FuncBodyStart() = (%d, %v), want the offset after %q in %q", offset, ok, c.before, c.src)
+		}
+	}
+}
+
+func TestContextParam(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"import \"context\"\nfunc f(ctx context.Context, n int) {\n\t‸\n}\n", "ctx"},
+		{"import stdctx \"context\"\nfunc f(c stdctx.Context) {\n\t‸\n}\n", "c"},
+		{"import . \"context\"\nfunc f(ctx Context) {\n\t‸\n}\n", "ctx"},
+		{"import \"context\"\nfunc (s *S) f(ctx context.Context) {\n\tgo func() {\n\t\t‸\n\t}()\n}\n", "ctx"},
+		{"import \"context\"\nfunc f(ctx context.Context) {\n\th := func(c context.Context) {\n\t\t‸\n\t}\n}\n", "c"},
+		{"import \"context\"\nfunc f(n int, ctx context.Context) {\n\t‸\n}\n", ""},
+		{"import \"context\"\nfunc f(_ context.Context) {\n\t‸\n}\n", ""},
+		{"import \"golang.org/x/net/context\"\nfunc f(ctx context.Context) {\n\t‸\n}\n", ""},
+		{"func f(ctx context.Context) {\n\t‸\n}\n", ""},
+	}
+	for _, c := range cases {
+		src := "package p\n" + c.src
+		cx := newTestCurCtx(t, src)
+		name, ok := cx.ContextParam()
+		if name != c.want || ok != (c.want != "") {
+			t.Errorf("ContextParam() = (%q, %v), want (%q, %v) in %q", name, ok, c.want, c.want != "", c.src)
+		}
+	}
+}
+
+func TestClosureResultType(t *testing.T) {
+	cases := []struct {
+		src   string
+		index int
+		want  string
+	}{
+		{"func f() error {\n\tslices.SortFunc(xs, func(a, b T) int { return ‸ })\n}\n", 0, "int"},
+		{"func f() error {\n\tslices.SortFunc(xs, func(a, b T) int {\n\t\treturn ‸\n\t})\n}\n", 0, "int"},
+		{"func f() {\n\tg(func() (*T, error) { return nil, ‸ })\n}\n", 1, "error"},
+		{"func f() {\n\tg(func() (n, m int, err error) {\n\t\treturn 1, ‸\n\t})\n}\n", 1, "int"},
+		{"func f() {\n\tg(func() (n, m int, err error) {\n\t\treturn 1, ‸\n\t})\n}\n", 2, "error"},
+		{"func f() {\n\tg(func() int { return ‸ })\n}\n", 1, ""},
+		{"func f() {\n\tg(func() { ‸ })\n}\n", 0, ""},
+		{"func f() int {\n\treturn ‸\n}\n", 0, ""},
+		{"func f() {\n\tg(func(‸) int { return 0 })\n}\n", 0, ""},
+	}
+	for _, c := range cases {
+		src := "package p\n" + c.src
+		cx := newTestCurCtx(t, src)
+		typ, ok := cx.ClosureResultType(c.index)
+		got := ""
+		if typ != nil {
+			got, _ = cx.Print(typ)
+		}
+		if got != c.want || ok != (c.want != "") {
+			t.Errorf("ClosureResultType(%d) = (`%s`, %v), want (`%s`, %v) in %q", c.index, got, ok, c.want, c.want != "", c.src)
+		}
+	}
+}
+
+func TestExpectedCallbackSignature(t *testing.T) {
+	decls := "package p\ntype Handler func(w Writer, r *Request)\nfunc SortFunc(xs []T, cmp func(a, b T) int) {}\nfunc Handle(pattern string, h Handler) {}\nfunc Each(fns ...func(int)) {}\n"
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"SortFunc(xs, func(‸) {})", "func(a, b T) int"},
+		{"SortFunc(xs, func(a, b T) int {\n\t\treturn ‸\n\t})", "func(a, b T) int"},
+		{"Handle(\"/\", func(w Writer, ‸) {})", "func(w Writer, r *Request)"},
+		{"Each(func(int) {}, func(‸) {})", "func(int)"},
+		{"SortFunc(func(‸) {})", ""},
+		{"f := func(‸) {}", ""},
+		{"sort.Slice(xs, func(‸) {})", ""},
+		{"SortFunc(xs, nil)\n\tfunc(‸) {}()", ""},
+	}
+	for _, c := range cases {
+		src := decls + "func f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		ft, ok := cx.ExpectedCallbackSignature()
+		got := ""
+		if ft != nil {
+			got, _ = cx.Print(ft)
+		}
+		if got != c.want || ok != (c.want != "") {
+			t.Errorf("ExpectedCallbackSignature() = (`%s`, %v), want (`%s`, %v) in %q", got, ok, c.want, c.want != "", c.src)
+		}
+	}
+}
+
+func TestHasNamedResults(t *testing.T) {
+	cases := []struct {
+		src  string
+		want bool
+	}{
+		{"package p\nfunc f() (n int, err error) {\n\t‸\n}\n", true},
+		{"package p\nfunc (t *T) f() (err error) {\n\t‸\n}\n", true},
+		{"package p\nfunc f() (int, error) {\n\t‸\n}\n", false},
+		{"package p\nfunc f() error {\n\t‸\n}\n", false},
+		{"package p\nfunc f() {\n\t‸\n}\n", false},
+		{"package p\nfunc f() (err error) {\n\tg := func() int {\n\t\t‸\n\t}\n}\n", false},
+		{"package p\nfunc f() {\n\tg := func() (ok bool) {\n\t\t‸\n\t}\n}\n", true},
+		{"package p\nvar x = ‸1\n", false},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		if got := cx.HasNamedResults(); got != c.want {
+			t.Errorf("HasNamedResults() = %v, want %v in %q", got, c.want, c.src)
+		}
+	}
+}
+
+func TestEnclosingFuncStats(t *testing.T) {
+	cases := []struct {
+		src                          string
+		statements, nesting, returns int
+		ok                           bool
+	}{
+		{"package p\nfunc f() {\n\t‸\n}\n", 0, 0, 0, true},
+		{"package p\nfunc f() int {\n\tx := 1\n\tx++\n\treturn ‸x\n}\n", 3, 0, 1, true},
+		{"package p\nfunc f() int {\n\tif x {\n\t\treturn 1\n\t}\n\treturn ‸2\n}\n", 3, 1, 2, true},
+		{"package p\nfunc f() {\n\tfor {\n\t\tswitch x {\n\t\tcase 1:\n\t\t\tif y {\n\t\t\t\tg()\n\t\t\t}\n\t\t}\n\t}\n\t‸\n}\n", 4, 3, 0, true},
+		{"package p\nfunc f() {\n\tswitch x {\n\tcase 1:\n\t\tg()\n\tdefault:\n\t}\n\t‸\n}\n", 2, 1, 0, true},
+		{"package p\nfunc f() {\n\tif x {\n\t\tg()\n\t}\n\t‸\n}\n", 2, 1, 0, true},
+		{"package p\nfunc f() {\n\tswitch v := x.(type) {\n\tcase int:\n\t\tswitch {\n\t\tcase v > 0:\n\t\t\tg()\n\t\t}\n\t}\n\t‸\n}\n", 4, 2, 0, true},
+		{"package p\nfunc f() {\n\tselect {\n\tcase <-c:\n\t\tg()\n\t}\n\t‸\n}\n", 2, 1, 0, true},
+		{"package p\nfunc f() {\n\tg := func() int {\n\t\treturn 1\n\t}\n\t‸g()\n}\n", 2, 0, 0, true},
+		{"package p\nfunc f() {\n\tg := func() int {\n\t\tx := 1\n\t\treturn ‸x\n\t}\n}\n", 2, 0, 1, true},
+		{"package p\nvar x = ‸1\n", 0, 0, 0, false},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		statements, nesting, returns, ok := cx.EnclosingFuncStats()
+		if statements != c.statements || nesting != c.nesting || returns != c.returns || ok != c.ok {
+			t.Errorf("EnclosingFuncStats() = (%d, %d, %d, %v), want (%d, %d, %d, %v) in %q",
+				statements, nesting, returns, ok, c.statements, c.nesting, c.returns, c.ok, c.src)
+		}
+	}
+}
+
+func TestInSingleReturnBody(t *testing.T) {
+	cases := []struct {
+		src  string
+		want bool
+	}{
+		{"package p\nfunc f() int {\n\treturn ‸1\n}\n", true},
+		{"package p\nfunc (t T) f() int { return t.‸n }\n", true},
+		{"package p\nvar f = func() int { return ‸1 }\n", true},
+		{"package p\nfunc f() int {\n\tx := 1\n\treturn ‸x\n}\n", false},
+		{"package p\nfunc f() int {\n\tif x {\n\t\treturn ‸1\n\t}\n}\n", false},
+		{"package p\nfunc f() int {\n\treturn 1\n}\nvar x = ‸1\n", false},
+		{"package p\nfunc f() func() int {\n\tx := 1\n\treturn func() int { return ‸x }\n}\n", true},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		ret, ok := cx.InSingleReturnBody()
+		if ok != c.want || (ret != nil) != c.want {
+			t.Errorf("InSingleReturnBody() = (%v, %v), want %v in %q", ret != nil, ok, c.want, c.src)
+		}
+	}
+}
+
+func TestEnclosingFuncExported(t *testing.T) {
+	cases := []struct {
+		src      string
+		exported bool
+		ok       bool
+	}{
+		{"package p\nfunc F() {\n\t‸\n}\n", true, true},
+		{"package p\nfunc f() {\n\t‸\n}\n", false, true},
+		{"package p\nfunc (t *T) M() {\n\t‸\n}\n", true, true},
+		{"package p\nfunc (t t) M() {\n\t‸\n}\n", false, true},
+		{"package p\nfunc (l *List[T]) Len() int {\n\treturn ‸0\n}\n", true, true},
+		{"package p\nfunc (t T) m() {\n\t‸\n}\n", false, true},
+		{"package p\nfunc F() {\n\tg := func() {\n\t\t‸\n\t}\n}\n", true, true},
+		{"package p\nvar x = ‸1\n", false, false},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		exported, ok := cx.EnclosingFuncExported()
+		if exported != c.exported || ok != c.ok {
+			t.Errorf("EnclosingFuncExported() = (%v, %v), want (%v, %v) in %q", exported, ok, c.exported, c.ok, c.src)
+		}
+	}
+}
+
+func TestReturnExprIndex(t *testing.T) {
+	cases := []struct {
+		src   string
+		index int
+		ok    bool
+	}{
+		{"return ‸", 0, true},
+		{"return x‸", 0, true},
+		{"return x, ‸", 1, true},
+		{"return x,‸", 1, true},
+		{"return x, f(a, b), ‸", 2, true},
+		{"return x, y‸.", 1, true},
+		{"return x,\n\t\t‸", 1, true},
+		{"return x, T{A: 1, B: 2}, ‸nil", 2, true},
+		{"‸", 0, false},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() (int, int, error) {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		index, ok := cx.ReturnExprIndex()
+		if index != c.index || ok != c.ok {
+			t.Errorf("ReturnExprIndex() = (%d, %v), want (%d, %v) in %q", index, ok, c.index, c.ok, c.src)
+		}
+	}
+}
+
+func TestDocStubInputs(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"package p\nfunc Open(name string, flag int) (f *File, err error) {\n\t‸\n}\n", "Open (name, flag) (f, err)"},
+		{"package p\n// ‸\nfunc Open(name string) error {\n}\n", "Open (name) ()"},
+		{"package p\nfunc (t *T) Len() int {\n\treturn ‸0\n}\n", "Len () ()"},
+		{"package p\nfunc F(int, string) (_ int, err error) {\n\t‸\n}\n", "F () (err)"},
+		{"package p\nfunc F(_ int, s ...string) {\n\t‸\n}\n", "F (s) ()"},
+		{"package p\nfunc F() {\n\tg := func(a int) {\n\t\t‸\n\t}\n}\n", "F () ()"},
+		{"package p\nvar x = ‸1\n", ""},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		name, params, results, ok := cx.DocStubInputs()
+		got := ""
+		if ok {
+			got = name + " (" + strings.Join(params, ", ") + ") (" + strings.Join(results, ", ") + ")"
+		}
+		if got != c.want {
+			t.Errorf("DocStubInputs() = %q, want %q in %q", got, c.want, c.src)
+		}
+	}
+}
+
+func TestEnclosingFuncHasDoc(t *testing.T) {
+	cases := []struct {
+		src    string
+		hasDoc bool
+		ok     bool
+	}{
+		{"package p\n// Open opens a file\nfunc Open() {\n\t‸\n}\n", true, true},
+		{"package p\n/* Open opens a file */\nfunc Open() {\n\t‸\n}\n", true, true},
+		{"package p\nfunc Open() {\n\t‸\n}\n", false, true},
+		{"package p\n// Open opens ‸\nfunc Open() {\n}\n", true, true},
+		{"package p\n// ‸\nfunc Open() {\n}\n", false, true},
+		{"package p\n// F does things\nfunc F() {\n\tg := func() {\n\t\t‸\n\t}\n}\n", true, true},
+		{"package p\n// x is one\nvar x = ‸1\n", false, false},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		hasDoc, ok := cx.EnclosingFuncHasDoc()
+		if hasDoc != c.hasDoc || ok != c.ok {
+			t.Errorf("EnclosingFuncHasDoc() = (%v, %v), want (%v, %v) in %q", hasDoc, ok, c.hasDoc, c.ok, c.src)
+		}
+	}
+}
+
+func TestCallArgType(t *testing.T) {
+	decls := "package p\nfunc open(name string, flag int, opts ...Option) {}\ntype T struct{}\nfunc (t *T) Set(key string, v bool) {}\n"
+	cases := []struct {
+		src      string
+		typ      string
+		variadic bool
+	}{
+		{"open(‸)", "string", false},
+		{"open(name, ‸)", "int", false},
+		{"open(name, 0, ‸)", "Option", true},
+		{"open(name, 0, a, b, ‸)", "Option", true},
+		{"var t T\n\tt.Set(k, ‸)", "bool", false},
+		{"t := &T{}\n\tt.Set(‸)", "string", false},
+		{"g := func(n float64) {}\n\tg(‸)", "float64", false},
+		{"func(b byte) {}(‸)", "byte", false},
+		{"fmt.Println(‸)", "", false},
+		{"T{}.Set(k, v, ‸)", "", false},
+		{"open := func() {}\n\topen(‸)", "", false},
+	}
+	for _, c := range cases {
+		src := decls + "func f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		typ, variadic, ok := cx.CallArgType()
+		got := ""
+		if typ != nil {
+			got, _ = cx.Print(typ)
+		}
+		if got != c.typ || variadic != c.variadic || ok != (c.typ != "") {
+			t.Errorf("CallArgType() = (`%s`, %v, %v), want (`%s`, %v, %v) in %q", got, variadic, ok, c.typ, c.variadic, c.typ != "", c.src)
+		}
+	}
+}
+
+func TestUnassignedNamedResults(t *testing.T) {
+	cases := []struct {
+		src   string
+		names string
+		ok    bool
+	}{
+		{"func f() (n int, err error) {\n\t‸\n}", "n err", true},
+		{"func f() (n int, err error) {\n\tn = 1\n\t‸\n}", "err", true},
+		{"func f() (n int, err error) {\n\t‸\n\tn = 1\n}", "n err", true},
+		{"func f() (n int, err error) {\n\tif true {\n\t\terr = g()\n\t}\n\t‸\n}", "n", true},
+		{"func f() (n int, err error) {\n\tn, err := 1, g()\n\t‸\n}", "", true},
+		{"func f() (n int, err error) {\n\tif true {\n\t\terr := g()\n\t\t_ = err\n\t}\n\t‸\n}", "n err", true},
+		{"func f() (n int, err error) {\n\tn++\n\tjson.Unmarshal(nil, &err)\n\t‸\n}", "", true},
+		{"func f() (n int, err error) {\n\tdefer func() {\n\t\terr = recover().(error)\n\t}()\n\t‸\n}", "n", true},
+		{"func f() (k string, _ int) {\n\tfor k = range m {\n\t\t‸\n\t}\n}", "", true},
+		{"func f() (n int, err error) {\n\tn = ‸\n}", "n err", true},
+		{"func f() (int, error) {\n\t‸\n}", "", false},
+		{"func f() {\n\t‸\n}", "", false},
+		{"func f() (n int, ‸err error) {\n}", "", false},
+	}
+	for _, c := range cases {
+		src := "package p\n" + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		names, ok := cx.UnassignedNamedResults()
+		got := strings.Join(names, " ")
+		if got != c.names || ok != c.ok {
+			t.Errorf("UnassignedNamedResults() = (`%s`, %v), want (`%s`, %v) in %q", got, ok, c.names, c.ok, c.src)
+		}
+	}
+}
+
+func TestReturnNilContext(t *testing.T) {
+	decls := "package p\ntype T struct{}\ntype List []T\ntype Fn func()\n"
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"func f() *T {\n\treturn nil‸\n}", "*T"},
+		{"func f() []T {\n\treturn ‸nil\n}", "[]T"},
+		{"func f() map[string]T {\n\treturn nil‸\n}", "map[string]T"},
+		{"func f() List {\n\treturn nil‸\n}", "List"},
+		{"func f() (l []T) {\n\tg := func() {}\n\tg()\n\treturn nil‸\n}", "[]T"},
+		{"func f() error {\n\treturn nil‸\n}", ""},
+		{"func f() Fn {\n\treturn nil‸\n}", ""},
+		{"func f() [2]T {\n\treturn nil‸\n}", ""},
+		{"func f() (*T, error) {\n\treturn nil, nil‸\n}", ""},
+		{"func f() *T {\n\treturn &T{}‸\n}", ""},
+		{"func f() *T {\n\tnil := &T{}\n\treturn nil‸\n}", ""},
+		{"func f() error {\n\tg := func() *T {\n\t\treturn nil‸\n\t}\n}", "*T"},
+	}
+	for _, c := range cases {
+		src := decls + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		typ, ok := cx.ReturnNilContext()
+		got := ""
+		if typ != nil {
+			got, _ = cx.Print(typ)
+		}
+		if got != c.want || ok != (c.want != "") {
+			t.Errorf("ReturnNilContext() = (`%s`, %v), want (`%s`, %v) in %q", got, ok, c.want, c.want != "", c.src)
+		}
+	}
+}
+
+func TestReturnArityMismatch(t *testing.T) {
+	cases := []struct {
+		src      string
+		want     int
+		got      int
+		mismatch bool
+	}{
+		{"func f() (int, error) {\n\treturn 0‸\n}", 2, 1, true},
+		{"func f() int {\n\treturn 0, nil‸\n}", 1, 2, true},
+		{"func f() (int, error) {\n\treturn‸\n}", 2, 0, true},
+		{"func f() (int, error) {\n\treturn 0, nil‸\n}", 2, 2, false},
+		{"func f() (n int, err error) {\n\treturn‸\n}", 2, 0, false},
+		{"func f() (int, error) {\n\treturn g()‸\n}", 2, 1, false},
+		{"func f() (int, error) {\n\treturn x‸\n}", 2, 1, true},
+		{"func f() {\n\treturn‸\n}", 0, 0, false},
+		{"func f() error {\n\tg := func() (int, bool) {\n\t\treturn 1‸\n\t}\n}", 2, 1, true},
+		{"func f() error {\n\tg := func() int {\n\t\treturn 1‸\n\t}\n}", 1, 1, false},
+	}
+	for _, c := range cases {
+		src := "package p\n" + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		want, got, mismatch := cx.ReturnArityMismatch()
+		if want != c.want || got != c.got || mismatch != c.mismatch {
+			t.Errorf("ReturnArityMismatch() = (%d, %d, %v), want (%d, %d, %v) in %q", want, got, mismatch, c.want, c.got, c.mismatch, c.src)
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package cursor
+
+import (
+	"bytes"
+	"go/ast"
+	"go/scanner"
+	"go/token"
+	"margo.sh/golang/goutil"
+	"strconv"
+)
+
+// ConstraintElemKind describes the kind of element in an interface body
+type ConstraintElemKind uint8
+
+const (
+	// UnknownConstraintElem is a position where either kind of element may be typed
+	UnknownConstraintElem ConstraintElemKind = iota
+
+	// MethodConstraintElem is a method element e.g. `String() string`
+	MethodConstraintElem
+
+	// TypeConstraintElem is a type element e.g. `~int | ~float64` or an embedded interface
+	TypeConstraintElem
+)
+
+// ConstraintInterfaceElem returns the kind of interface element the cursor is on.
+// ok is true iff the cursor is in an interface body (InterfaceBodyScope).
+//
+// kind is UnknownConstraintElem if the cursor is not on an element, e.g. on an empty line.
+// A lone identifier e.g. `interface { Name }` is reported as TypeConstraintElem
+// because, syntactically, it's an embedded type until `(` is typed.
+func (cx *CurCtx) ConstraintInterfaceElem() (kind ConstraintElemKind, ok bool) {
+	var it *ast.InterfaceType
+	if !cx.Scope.Is(InterfaceBodyScope) || !cx.Set(&it) || it.Methods == nil {
+		return UnknownConstraintElem, false
+	}
+	for _, f := range it.Methods.List {
+		if !goutil.NodeEnclosesPos(f, cx.TokenPos) {
+			continue
+		}
+		if len(f.Names) != 0 {
+			return MethodConstraintElem, true
+		}
+		return TypeConstraintElem, true
+	}
+	return UnknownConstraintElem, true
+}
+
+// TypeDeclTypeParams returns the type params of the generic type declaration whose type param list the cursor is in
+// e.g. `K comparable` and `V any` in `type Map[K comparable, V ‸any] struct{}`.
+//
+// While the first param is being typed e.g. `type Stack[T ‸]`, the declaration is parsed as an array type,
+// so fields is empty, but ok is still true.
+func (cx *CurCtx) TypeDeclTypeParams() (fields []*ast.Field, ok bool) {
+	_, n := cx.typeParamList()
+	ts, _ := n.(*ast.TypeSpec)
+	if ts == nil {
+		return nil, false
+	}
+	if ts.TypeParams == nil {
+		return []*ast.Field{}, true
+	}
+	return ts.TypeParams.List, true
+}
+
+// typeParamList returns the opening bracket of the type param list the cursor is in,
+// and the *ast.FuncType or *ast.TypeSpec that declares it.
+func (cx *CurCtx) typeParamList() (open token.Pos, n ast.Node) {
+	for i := len(cx.Nodes) - 1; i >= 0; i-- {
+		n = cx.Nodes[i]
+		switch x := n.(type) {
+		case *ast.FuncType:
+			if x.TypeParams != nil {
+				open = x.TypeParams.Opening
+			}
+		case *ast.TypeSpec:
+			if x.TypeParams != nil {
+				open = x.TypeParams.Opening
+			} else if at, ok := x.Type.(*ast.ArrayType); ok && x.Name != nil && at.Lbrack == x.Name.End() {
+				// `type T[P ‸]` is parsed as an array type until the constraint is typed.
+				// Array types are formatted with a space before the `[`, so this is unlikely to be one.
+				open = at.Lbrack
+			}
+		case *ast.BlockStmt:
+			return token.NoPos, nil
+		default:
+			continue
+		}
+		if !open.IsValid() || open >= cx.TokenPos {
+			return token.NoPos, nil
+		}
+		if _, ok := cx.typeParamSegment(open); !ok {
+			return token.NoPos, nil
+		}
+		return open, n
+	}
+	return token.NoPos, nil
+}
+
+// typeParamSegment returns the source of the type param before the cursor, from the last comma,
+// in the type param list opened at open.
+// ok is false if the list is closed before the cursor.
+func (cx *CurCtx) typeParamSegment(open token.Pos) (seg []byte, ok bool) {
+	start := cx.TokenFile.Offset(open) + 1
+	if cx.srcPos < start {
+		return nil, false
+	}
+	src := cx.Src[start:cx.srcPos]
+	var sc scanner.Scanner
+	sc.Init(token.NewFileSet().AddFile("", -1, len(src)), src, nil, 0)
+	segStart, depth := 0, 0
+	for {
+		pos, tok, _ := sc.Scan()
+		switch tok {
+		case token.EOF:
+			return src[segStart:], true
+		case token.LPAREN, token.LBRACK, token.LBRACE:
+			depth++
+		case token.RPAREN, token.RBRACE:
+			depth--
+		case token.RBRACK:
+			if depth == 0 {
+				return nil, false
+			}
+			depth--
+		case token.COMMA:
+			if depth == 0 {
+				segStart = int(pos)
+			}
+		}
+	}
+}
+
+// inConstraintPos returns true if the cursor is in the constraint of a type param
+// i.e. after the name e.g. `[T ‸]` or `[K comparable, V a‸]`, but not `[K, ‸]`
+func (cx *CurCtx) inConstraintPos(open token.Pos) bool {
+	seg, ok := cx.typeParamSegment(open)
+	if !ok {
+		return false
+	}
+	var sc scanner.Scanner
+	sc.Init(token.NewFileSet().AddFile("", -1, len(seg)), seg, nil, 0)
+	if _, tok, _ := sc.Scan(); tok != token.IDENT {
+		return false
+	}
+	if _, tok, _ := sc.Scan(); tok != token.EOF && tok != token.SEMICOLON {
+		return true
+	}
+	// the name is followed by a space e.g. `[T ‸]`
+	return len(bytes.TrimRight(seg, " \t")) < len(seg)
+}
+
+// ReceiverTypeParams returns the type params declared by the receiver of the method enclosing the cursor
+// e.g. `K` and `V` in `func (m *Map[K, V]) Get(k K) V`. Blank (`_`) params are omitted.
+// ok is false if the cursor is not in a method of a generic type.
+func (cx *CurCtx) ReceiverTypeParams() ([]*ast.Ident, bool) {
+	var fd *ast.FuncDecl
+	if !cx.Set(&fd) {
+		return nil, false
+	}
+	l := receiverTypeParams(fd)
+	return l, len(l) != 0
+}
+
+// receiverTypeParams returns the type params declared by the receiver of fd
+func receiverTypeParams(fd *ast.FuncDecl) []*ast.Ident {
+	if fd.Recv == nil || len(fd.Recv.List) == 0 {
+		return nil
+	}
+	typ := fd.Recv.List[0].Type
+	if x, ok := typ.(*ast.StarExpr); ok {
+		typ = x.X
+	}
+	var exprs []ast.Expr
+	switch x := typ.(type) {
+	case *ast.IndexExpr:
+		exprs = []ast.Expr{x.Index}
+	case *ast.IndexListExpr:
+		exprs = x.Indices
+	}
+	var l []*ast.Ident
+	for _, x := range exprs {
+		if id, ok := x.(*ast.Ident); ok && id.Name != "_" {
+			l = append(l, id)
+		}
+	}
+	return l
+}
+
+var (
+	// knownInterfaceMethods is the set of methods of commonly used interfaces from the standard library,
+	// keyed by import path and name
+	knownInterfaceMethods = map[string][]string{
+		"fmt.Stringer":   {"String"},
+		"io.Closer":      {"Close"},
+		"io.Reader":      {"Read"},
+		"io.ReadCloser":  {"Read", "Close"},
+		"io.Writer":      {"Write"},
+		"io.WriteCloser": {"Write", "Close"},
+		"sort.Interface": {"Len", "Less", "Swap"},
+	}
+)
+
+// TypeParamConstraintMethods returns the names of the methods of the constraint of the type param name,
+// declared by the function or method enclosing the cursor, including its receiver
+// e.g. `[String]` for `T` in `func F[T fmt.Stringer](x T) { x.‸ }`.
+//
+// Only constraints that are interfaces declared in the file, interface literals, `any`, `comparable`, `error`
+// and a few well-known interfaces from the standard library e.g. `fmt.Stringer` are supported.
+// ok is false for constraints with type sets e.g. `~int | ~string`, because their methods can't be known syntactically.
+func (cx *CurCtx) TypeParamConstraintMethods(name string) ([]string, bool) {
+	var fd *ast.FuncDecl
+	if !cx.Set(&fd) {
+		return nil, false
+	}
+	constraint := typeParamConstraint(fd.Type.TypeParams, name, -1)
+	if constraint == nil {
+		constraint = cx.receiverTypeParamConstraint(fd, name)
+	}
+	if constraint == nil {
+		return nil, false
+	}
+	return cx.interfaceMethodNames(constraint, 0)
+}
+
+// typeParamConstraint returns the constraint of the type param name, or at index if index >= 0, in fl
+func typeParamConstraint(fl *ast.FieldList, name string, index int) ast.Expr {
+	if fl == nil {
+		return nil
+	}
+	i := 0
+	for _, f := range fl.List {
+		for _, id := range f.Names {
+			if i == index || (index < 0 && id.Name == name) {
+				return f.Type
+			}
+			i++
+		}
+	}
+	return nil
+}
+
+// receiverTypeParamConstraint returns the constraint of the receiver type param name of fd,
+// as declared by the type declaration of the receiver e.g. `any` for `T` in `type List[T any] []T`
+func (cx *CurCtx) receiverTypeParamConstraint(fd *ast.FuncDecl, name string) ast.Expr {
+	if fd.Recv == nil || len(fd.Recv.List) == 0 || cx.AstFile == nil {
+		return nil
+	}
+	typ := fd.Recv.List[0].Type
+	if x, ok := typ.(*ast.StarExpr); ok {
+		typ = x.X
+	}
+	var base ast.Expr
+	var params []ast.Expr
+	switch x := typ.(type) {
+	case *ast.IndexExpr:
+		base, params = x.X, []ast.Expr{x.Index}
+	case *ast.IndexListExpr:
+		base, params = x.X, x.Indices
+	}
+	tn, _ := base.(*ast.Ident)
+	if tn == nil {
+		return nil
+	}
+	index := -1
+	for i, x := range params {
+		if id, ok := x.(*ast.Ident); ok && id.Name == name {
+			index = i
+		}
+	}
+	if index < 0 {
+		return nil
+	}
+	for _, decl := range cx.AstFile.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			if ts, ok := spec.(*ast.TypeSpec); ok && ts.Name.Name == tn.Name {
+				return typeParamConstraint(ts.TypeParams, "", index)
+			}
+		}
+	}
+	return nil
+}
+
+// interfaceMethodNames returns the names of the methods of the interface typ, including embedded interfaces.
+// ok is false if typ is not a known interface, or has a type set.
+func (cx *CurCtx) interfaceMethodNames(typ ast.Expr, depth int) (names []string, ok bool) {
+	// limit the depth to guard against cycles like `type A interface{ B }; type B interface{ A }`
+	if depth > 10 {
+		return nil, false
+	}
+	switch x := typ.(type) {
+	case *ast.ParenExpr:
+		return cx.interfaceMethodNames(x.X, depth+1)
+	case *ast.Ident:
+		if t, ok := cx.typeDecl(x.Name); ok {
+			return cx.interfaceMethodNames(t, depth+1)
+		}
+		if cx.isDeclared(x.Name) {
+			return nil, false
+		}
+		switch x.Name {
+		case "any", "comparable":
+			return []string{}, true
+		case "error":
+			return []string{"Error"}, true
+		}
+	case *ast.SelectorExpr:
+		pkg, _ := x.X.(*ast.Ident)
+		if pkg == nil || cx.AstFile == nil {
+			return nil, false
+		}
+		for _, spec := range cx.AstFile.Imports {
+			if importSpecName(spec) != pkg.Name {
+				continue
+			}
+			p, _ := strconv.Unquote(spec.Path.Value)
+			if l, ok := knownInterfaceMethods[p+"."+x.Sel.Name]; ok {
+				return append([]string{}, l...), true
+			}
+		}
+	case *ast.InterfaceType:
+		names = []string{}
+		if x.Methods == nil {
+			return names, true
+		}
+		for _, f := range x.Methods.List {
+			if _, isFunc := f.Type.(*ast.FuncType); isFunc {
+				for _, id := range f.Names {
+					names = append(names, id.Name)
+				}
+				continue
+			}
+			l, ok := cx.interfaceMethodNames(f.Type, depth+1)
+			if !ok {
+				return nil, false
+			}
+			names = append(names, l...)
+		}
+		return names, true
+	}
+	return nil, false
+}
+
+// GenericCallInference returns the generic function called by the call enclosing the cursor,
+// and whether the call supplies explicit type arguments e.g. `Map[int, string](‸)`,
+// or relies on inference e.g. `Map(xs, ‸)`.
+//
+// Only functions declared in the file are recognised, so ok is false for e.g. imported generic functions.
+func (cx *CurCtx) GenericCallInference() (fn ast.Expr, hasExplicitTypeArgs bool, ok bool) {
+	call := cx.enclosingCallArgs()
+	if call == nil {
+		return nil, false, false
+	}
+	fn = call.Fun
+	switch x := fn.(type) {
+	case *ast.IndexExpr:
+		fn, hasExplicitTypeArgs = x.X, true
+	case *ast.IndexListExpr:
+		fn, hasExplicitTypeArgs = x.X, true
+	}
+	id, _ := fn.(*ast.Ident)
+	if id == nil || !cx.isGenericFunc(id.Name) {
+		return nil, false, false
+	}
+	return fn, hasExplicitTypeArgs, true
+}
+
+// isGenericFunc returns true if name is a top-level function with type params, declared in the file
+func (cx *CurCtx) isGenericFunc(name string) bool {
+	if cx.AstFile == nil {
+		return false
+	}
+	for _, d := range cx.localDecls() {
+		if d.Name.Name == name {
+			// shadowed
+			return false
+		}
+	}
+	for _, d := range cx.AstFile.Decls {
+		fd, ok := d.(*ast.FuncDecl)
+		if ok && fd.Recv == nil && fd.Name.Name == name {
+			return fd.Type.TypeParams != nil && len(fd.Type.TypeParams.List) != 0
+		}
+	}
+	return false
+}
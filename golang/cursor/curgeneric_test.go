@@ -0,0 +1,194 @@
+package cursor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConstraintInterfaceElem(t *testing.T) {
+	cases := []struct {
+		src  string
+		kind ConstraintElemKind
+		ok   bool
+	}{
+		{"package p\ntype C interface {\n\t~i‸nt | ~float64\n\tString() string\n}\n", TypeConstraintElem, true},
+		{"package p\ntype C interface {\n\t~int | ~flo‸at64\n\tString() string\n}\n", TypeConstraintElem, true},
+		{"package p\ntype C interface {\n\t~int | ~float64\n\tStr‸ing() string\n}\n", MethodConstraintElem, true},
+		{"package p\ntype C interface {\n\t~int | ~float64\n\tString() str‸ing\n}\n", MethodConstraintElem, true},
+		{"package p\ntype C interface {\n\t~int | ~float64\n\n\t‸\n\n\tString() string\n}\n", UnknownConstraintElem, true},
+		{"package p\ntype C interface {\n\tfmt.Str‸inger\n}\n", TypeConstraintElem, true},
+		{"package p\ntype S struct {\n\tN i‸nt\n}\n", UnknownConstraintElem, false},
+		{"package p\nfunc f() {\n\tx‸ := 1\n}\n", UnknownConstraintElem, false},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		kind, ok := cx.ConstraintInterfaceElem()
+		if kind != c.kind || ok != c.ok {
+			t.Errorf("ConstraintInterfaceElem() = (%v, %v), want (%v, %v) in %q", kind, ok, c.kind, c.ok, c.src)
+		}
+	}
+}
+
+func TestGenericCallInference(t *testing.T) {
+	decls := "package p\nfunc Map[T, U any](l []T, f func(T) U) []U { return nil }\nfunc Id[T any](v T) T { return v }\nfunc Plain(v int) int { return v }\n"
+	cases := []struct {
+		src      string
+		fn       string
+		explicit bool
+	}{
+		{"Map(xs, ‸)", "Map", false},
+		{"Map[int, string](xs, ‸)", "Map", true},
+		{"Id[int](‸)", "Id", true},
+		{"Id(‸)", "Id", false},
+		{"Plain(‸)", "", false},
+		{"fns[0](‸)", "", false},
+		{"slices.Index(l, ‸)", "", false},
+		{"Id(Plain(‸))", "", false},
+		{"Id := func(v int) {}\n\tId(‸)", "", false},
+	}
+	for _, c := range cases {
+		src := decls + "func f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		fn, explicit, ok := cx.GenericCallInference()
+		got := ""
+		if fn != nil {
+			got, _ = cx.Print(fn)
+		}
+		if got != c.fn || explicit != c.explicit || ok != (c.fn != "") {
+			t.Errorf("GenericCallInference() = (`%s`, %v, %v), want (`%s`, %v, %v) in %q", got, explicit, ok, c.fn, c.explicit, c.fn != "", c.src)
+		}
+	}
+}
+
+func TestReceiverTypeParams(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"func (l List[T]) Get(i int) T {\n\t‸\n}\n", "T"},
+		{"func (m *Map[K, V]) Get(k K) V {\n\t‸\n}\n", "K, V"},
+		{"func (m *Map[K, _]) Len() int {\n\t‸\n}\n", "K"},
+		{"func (m *Map[K, V]) Each(f func(K, V)) {\n\tg := func() {\n\t\t‸\n\t}\n}\n", "K, V"},
+		{"func (t *T) Get() {\n\t‸\n}\n", ""},
+		{"func Get[T any]() {\n\t‸\n}\n", ""},
+	}
+	for _, c := range cases {
+		src := "package p\n" + c.src
+		cx := newTestCurCtx(t, src)
+		params, ok := cx.ReceiverTypeParams()
+		got := ""
+		for i, id := range params {
+			if i > 0 {
+				got += ", "
+			}
+			got += id.Name
+		}
+		if got != c.want || ok != (c.want != "") {
+			t.Errorf("ReceiverTypeParams() = (`%s`, %v), want (`%s`, %v) in %q", got, ok, c.want, c.want != "", c.src)
+		}
+		if ok && !strings.Contains(strings.Join(cx.LocalTypeNames(), " "), params[0].Name) {
+			t.Errorf("LocalTypeNames() = %v, want it to include %s in %q", cx.LocalTypeNames(), params[0].Name, c.src)
+		}
+	}
+}
+
+func TestTypeDeclTypeParams(t *testing.T) {
+	cases := []struct {
+		src        string
+		params     string
+		ok         bool
+		constraint bool
+	}{
+		{"type Map[K comparable, V ‸]", "K, V", true, true},
+		{"type Map[K comparable, V a‸ny] struct{}", "K, V", true, true},
+		{"type Map[K comparable, ‸V any] struct{}", "K, V", true, false},
+		{"type Map[K ‸] struct{}", "", true, true},
+		{"type Stack[‸]", "", true, false},
+		{"type Stack[T ‸]", "", true, true},
+		{"type Set[T comp‸arable] map[T]struct{}", "T", true, true},
+		{"type Set[T comparable] map[T]‸struct{}", "", false, false},
+		{"type Arr [‸]int", "", false, false},
+		{"type T struct{ ‸ }", "", false, false},
+	}
+	for _, c := range cases {
+		src := "package p\n" + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		fields, ok := cx.TypeDeclTypeParams()
+		got := ""
+		for _, f := range fields {
+			for _, id := range f.Names {
+				if got != "" {
+					got += ", "
+				}
+				got += id.Name
+			}
+		}
+		if got != c.params || ok != c.ok {
+			t.Errorf("TypeDeclTypeParams() = (`%s`, %v), want (`%s`, %v) in %q", got, ok, c.params, c.ok, c.src)
+		}
+		if got := cx.Scope.Is(TypeParamScope); got != c.ok {
+			t.Errorf("Scope.Is(TypeParamScope) = %v, want %v in %q", got, c.ok, c.src)
+		}
+		if got := cx.Scope.Is(ConstraintScope); got != c.constraint {
+			t.Errorf("Scope.Is(ConstraintScope) = %v, want %v in %q", got, c.constraint, c.src)
+		}
+	}
+}
+
+func TestFuncTypeParamScopes(t *testing.T) {
+	cases := []struct {
+		src        string
+		typeParam  bool
+		constraint bool
+	}{
+		{"func F[T ‸]() {}", true, true},
+		{"func F[T any, ‸]() {}", true, false},
+		{"func F[T any](‸) {}", false, false},
+		{"func F[T any]() {\n\t‸\n}", false, false},
+	}
+	for _, c := range cases {
+		src := "package p\n" + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		if got := cx.Scope.Is(TypeParamScope); got != c.typeParam {
+			t.Errorf("Scope.Is(TypeParamScope) = %v, want %v in %q", got, c.typeParam, c.src)
+		}
+		if got := cx.Scope.Is(ConstraintScope); got != c.constraint {
+			t.Errorf("Scope.Is(ConstraintScope) = %v, want %v in %q", got, c.constraint, c.src)
+		}
+	}
+}
+
+func TestTypeParamConstraintMethods(t *testing.T) {
+	decls := "package p\nimport (\n\t\"fmt\"\n\tstdio \"io\"\n)\ntype Named interface{ Name() string }\ntype Entity interface {\n\tNamed\n\tID() int\n}\ntype Number interface{ ~int | ~float64 }\ntype List[T Named, E any] []T\n"
+	cases := []struct {
+		src   string
+		name  string
+		names string
+		ok    bool
+	}{
+		{"func F[T fmt.Stringer](x T) {\n\tx.‸\n}", "T", "String", true},
+		{"func F[T Named](x T) {\n\tx.‸\n}", "T", "Name", true},
+		{"func F[T Entity](x T) {\n\tx.‸\n}", "T", "Name ID", true},
+		{"func F[K comparable, V interface{ Len() int }](v V) {\n\tv.‸\n}", "V", "Len", true},
+		{"func F[K comparable, V any](v V) {\n\t‸\n}", "K", "", true},
+		{"func F[T error](err T) {\n\terr.‸\n}", "T", "Error", true},
+		{"func F[R stdio.ReadCloser](r R) {\n\tr.‸\n}", "R", "Read Close", true},
+		{"func (l List[E, _]) F() {\n\t‸\n}", "E", "Name", true},
+		{"func (l *List[T, U]) F() {\n\t‸\n}", "U", "", true},
+		{"func F[T Number](x T) {\n\tx‸\n}", "T", "", false},
+		{"func F[T interface{ ~int; String() string }](x T) {\n\tx‸\n}", "T", "", false},
+		{"func F[T io.Reader](x T) {\n\tx‸\n}", "T", "", false},
+		{"func F[T pkg.Iface](x T) {\n\tx‸\n}", "T", "", false},
+		{"func F[T any](x T) {\n\tx‸\n}", "U", "", false},
+		{"func F(x int) {\n\tx‸\n}", "T", "", false},
+	}
+	for _, c := range cases {
+		src := decls + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		names, ok := cx.TypeParamConstraintMethods(c.name)
+		got := strings.Join(names, " ")
+		if got != c.names || ok != c.ok {
+			t.Errorf("TypeParamConstraintMethods(%q) = (`%s`, %v), want (`%s`, %v) in %q", c.name, got, ok, c.names, c.ok, c.src)
+		}
+	}
+}
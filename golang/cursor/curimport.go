@@ -0,0 +1,263 @@
+package cursor
+
+import (
+	"bytes"
+	"go/ast"
+	"go/scanner"
+	"go/token"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	importVersionSuffixPat = regexp.MustCompile(`^v[0-9]+$`)
+
+	// importGenericNamePat matches last import path segments that are API versions, not major version suffixes,
+	// so they're the package name, but too generic to be used without an alias e.g. `v1` in `k8s.io/api/core/v1`
+	importGenericNamePat = regexp.MustCompile(`^v([01]|[0-9]+(alpha|beta)[0-9]*)$`)
+)
+
+// ImportPathPrefix returns the partial import path before the cursor
+// e.g. `github.com/` in `import "github.com/‸`.
+// ok is true iff the cursor is inside an import path, even if the closing quote hasn't been typed yet.
+func (cx *CurCtx) ImportPathPrefix() (prefix string, ok bool) {
+	if !cx.Scope.Is(ImportPathScope) {
+		return "", false
+	}
+	lit := cx.BasicLit
+	if lit == nil || cx.ImportSpec == nil {
+		return cx.scanImportPathPrefix()
+	}
+	start := cx.TokenFile.Offset(lit.Pos())
+	end := start + len(lit.Value)
+	if cx.srcPos <= start || cx.srcPos > len(cx.Src) {
+		return "", false
+	}
+	if s := lit.Value; len(s) >= 2 && s[0] == s[len(s)-1] && cx.srcPos >= end {
+		// we're after the closing quote
+		return "", false
+	}
+	return string(cx.Src[start+1 : cx.srcPos]), true
+}
+
+// ImportPathSegments returns the segments of the import path before the cursor.
+// The last segment is the one being typed and is empty after a `/`
+// e.g. `[github.com user ""]` in `import "github.com/user/‸`.
+//
+// Major version suffixes are joined to the preceding segment because they're not separate packages
+// e.g. `[github.com user mod/v2 ""]` in `import "github.com/user/mod/v2/‸`.
+// onLast is true if there are no more segments after the cursor.
+func (cx *CurCtx) ImportPathSegments() (segments []string, onLast bool, ok bool) {
+	prefix, ok := cx.ImportPathPrefix()
+	if !ok {
+		return nil, false, false
+	}
+	parts := strings.Split(prefix, "/")
+	for i, s := range parts {
+		// the last segment is still being typed so it's not (yet) a version suffix
+		if n := len(segments); n > 0 && i < len(parts)-1 && importVersionSuffixPat.MatchString(s) {
+			segments[n-1] += "/" + s
+			continue
+		}
+		segments = append(segments, s)
+	}
+
+	rest := cx.Src[cx.srcPos:]
+	if i := bytes.IndexAny(rest, "\"`\n"); i >= 0 {
+		rest = rest[:i]
+	}
+	return segments, bytes.IndexByte(rest, '/') < 0, true
+}
+
+// BlankImportContext returns the import spec the cursor is on iff it's a blank import e.g. `_ "embed"`.
+// hasComment is true if the import has a doc or trailing comment e.g. justifying its side effects.
+func (cx *CurCtx) BlankImportContext() (spec *ast.ImportSpec, hasComment bool, ok bool) {
+	spec = cx.ImportSpec
+	if spec == nil || spec.Name == nil || spec.Name.Name != "_" {
+		return nil, false, false
+	}
+	hasComment = spec.Doc.Text() != "" || spec.Comment.Text() != ""
+	return spec, hasComment, true
+}
+
+// scanImportPathPrefix is a token-based fallback for ImportPathPrefix
+// for when the parser doesn't produce an ImportSpec for the partial path.
+func (cx *CurCtx) scanImportPathPrefix() (prefix string, ok bool) {
+	src := cx.Src[lineStart(cx.Src, cx.srcPos):cx.srcPos]
+	var sc scanner.Scanner
+	sc.Init(token.NewFileSet().AddFile("", -1, len(src)), src, nil, 0)
+	toks := []token.Token{}
+	path := ""
+	for {
+		_, tok, lit := sc.Scan()
+		if tok == token.EOF || (tok == token.SEMICOLON && lit == "\n") {
+			break
+		}
+		toks = append(toks, tok)
+		if tok == token.STRING {
+			path = lit
+		}
+	}
+	if len(toks) != 0 && toks[0] == token.IMPORT {
+		toks = toks[1:]
+	} else if gd := cx.GenDecl; gd == nil || gd.Tok != token.IMPORT {
+		return "", false
+	}
+	if len(toks) == 2 && (toks[0] == token.IDENT || toks[0] == token.PERIOD) {
+		toks = toks[1:]
+	}
+	if len(toks) != 1 || toks[0] != token.STRING {
+		return "", false
+	}
+	if len(path) >= 2 && path[0] == path[len(path)-1] {
+		// the string is already terminated
+		return "", false
+	}
+	return path[1:], true
+}
+
+// UnresolvedPackageSelector returns the base and member names of the selector at the cursor
+// e.g. `strings` and `Rep` in `strings.Rep‸`,
+// iff the base is an identifier that isn't declared in the file nor the name of an import.
+// sel is empty if the member hasn't been typed yet.
+//
+// It's intended to allow auto-importing the package e.g. when typing `strings.` before importing it.
+func (cx *CurCtx) UnresolvedPackageSelector() (name string, sel string, ok bool) {
+	var se *ast.SelectorExpr
+	if !cx.Set(&se) || cx.TokenPos <= se.X.End() {
+		return "", "", false
+	}
+	id, _ := se.X.(*ast.Ident)
+	if id == nil || id.Name == "_" {
+		return "", "", false
+	}
+	name = id.Name
+	if cx.isDeclared(name) || cx.importNames()[name] {
+		return "", "", false
+	}
+	if sel = se.Sel.Name; sel == "_" {
+		// the parser fills in missing identifiers with `_`
+		sel = ""
+	}
+	return name, sel, true
+}
+
+// importNames returns the set of (possibly guessed) names of the file's imports
+func (cx *CurCtx) importNames() map[string]bool {
+	names := map[string]bool{}
+	if cx.AstFile == nil {
+		return names
+	}
+	for _, spec := range cx.AstFile.Imports {
+		names[importSpecName(spec)] = true
+	}
+	return names
+}
+
+// importSpecName returns the name by which spec is referred to in the file.
+// If the import isn't named, the name is guessed from the import path.
+func importSpecName(spec *ast.ImportSpec) string {
+	if spec.Name != nil {
+		return spec.Name.Name
+	}
+	p, _ := strconv.Unquote(spec.Path.Value)
+	return importPathName(p)
+}
+
+// importPathName guesses the package name of the import path p
+// skipping version suffixes e.g. `yaml` for `gopkg.in/yaml.v2` or `chi` for `github.com/go-chi/chi/v5`
+func importPathName(p string) string {
+	dir, nm := path.Split(path.Clean(p))
+	if importVersionSuffixPat.MatchString(nm) && dir != "" {
+		nm = path.Base(dir)
+	}
+	if i := len(nm) - 3; i > 0 && nm[i:i+2] == ".v" {
+		nm = nm[:i]
+	}
+	return nm
+}
+
+// SuggestImportAlias returns the name by which the package importPath should be imported
+// so that it doesn't collide with the file's imports, top-level declarations or DeclaredNames.
+// needsAlias is true if the name differs from the package's default name,
+// or the default name is too generic e.g. `corev1` for `k8s.io/api/core/v1`.
+//
+// If importPath is already imported, its existing name is returned.
+func (cx *CurCtx) SuggestImportAlias(importPath string) (alias string, needsAlias bool) {
+	if cx.AstFile != nil {
+		for _, spec := range cx.AstFile.Imports {
+			if p, _ := strconv.Unquote(spec.Path.Value); p == importPath {
+				return importSpecName(spec), spec.Name != nil
+			}
+		}
+	}
+
+	segments := strings.Split(strings.Trim(path.Clean(importPath), "/"), "/")
+	n := len(segments) - 1
+	if n > 0 && importVersionSuffixPat.MatchString(segments[n]) && !importGenericNamePat.MatchString(segments[n]) {
+		segments, n = segments[:n], n-1
+	}
+	name := importAliasIdent(importPathName(importPath))
+	if importGenericNamePat.MatchString(segments[n]) {
+		name = segments[n]
+		if n > 0 {
+			n--
+			name = importAliasIdent(segments[n]) + name
+			needsAlias = true
+		}
+	}
+	needsAlias = needsAlias || name != importPathName(importPath)
+
+	taken := cx.importNames()
+	for nm := range cx.topLevelNames() {
+		taken[nm] = true
+	}
+	for _, nm := range cx.DeclaredNames() {
+		taken[nm] = true
+	}
+	if !taken[name] {
+		return name, needsAlias
+	}
+	if n > 0 {
+		if nm := importAliasIdent(segments[n-1]) + name; !taken[nm] {
+			return nm, true
+		}
+	}
+	for i := 2; ; i++ {
+		if nm := name + strconv.Itoa(i); !taken[nm] {
+			return nm, true
+		}
+	}
+}
+
+// importAliasIdent returns s without the characters that aren't allowed in identifiers e.g. `goyaml` for `go-yaml`
+func importAliasIdent(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+	if s == "" || unicode.IsDigit(rune(s[0])) {
+		s = "pkg" + s
+	}
+	return s
+}
+
+// KnownStdlibPackage returns the import path of the standard library package named name
+// e.g. `bytes` for `bytes` or `net/http` for `http`, so it can be imported when e.g. UnresolvedPackageSelector
+// reports `bytes.Buf‸` without `bytes` being imported.
+//
+// Packages are looked up by their package name, not the last segment of their import path, e.g. `math/rand/v2` is named `rand`.
+// Where several packages have the same name, the most commonly used one is returned e.g. `math/rand` for `rand`.
+// ok is false if name is declared in the file, because it then doesn't refer to a package.
+func (cx *CurCtx) KnownStdlibPackage(name string) (importPath string, ok bool) {
+	if cx.isDeclared(name) {
+		return "", false
+	}
+	importPath, ok = stdlibPackages[name]
+	return importPath, ok
+}
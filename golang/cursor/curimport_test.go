@@ -0,0 +1,165 @@
+package cursor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImportPathPrefix(t *testing.T) {
+	cases := []struct {
+		src    string
+		prefix string
+		ok     bool
+	}{
+		{"package p\nimport \"git‸", "git", true},
+		{"package p\nimport \"‸", "", true},
+		{"package p\nimport \"git‸\n\nfunc f() {}\n", "git", true},
+		{"package p\nimport (\n\t\"fmt\"\n\t\"github.com/‸\n)\n", "github.com/", true},
+		{"package p\nimport (\n\t\"fmt\"\n\tx \"git‸\n)\n", "git", true},
+		{"package p\nimport (\n\t\"fmt\"\n\t\"github.com/‸\"\n)\n", "github.com/", true},
+		{"package p\nimport (\n\t\"f‸mt\"\n)\n", "f", true},
+		{"package p\nimport \"fmt\"‸\n", "", false},
+		{"package p\nvar s = \"git‸\"\n", "", false},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		prefix, ok := cx.ImportPathPrefix()
+		if prefix != c.prefix || ok != c.ok {
+			t.Errorf("ImportPathPrefix() = (%q, %v), want (%q, %v) in %q", prefix, ok, c.prefix, c.ok, c.src)
+		}
+	}
+}
+
+func TestImportPathSegments(t *testing.T) {
+	cases := []struct {
+		src      string
+		segments string
+		onLast   bool
+		ok       bool
+	}{
+		{"package p\nimport \"github.com/user/‸\"\n", "github.com|user|", true, true},
+		{"package p\nimport \"github.com/us‸\"\n", "github.com|us", true, true},
+		{"package p\nimport \"github.com/user/mod/v2/‸\"\n", "github.com|user|mod/v2|", true, true},
+		{"package p\nimport \"github.com/user/mod/v2‸\"\n", "github.com|user|mod|v2", true, true},
+		{"package p\nimport \"github.com/us‸er/mod\"\n", "github.com|us", false, true},
+		{"package p\nimport (\n\t\"fmt\"\n\t\"net/‸\n)\n", "net|", true, true},
+		{"package p\nimport \"‸\"\n", "", true, true},
+		{"package p\nvar s = \"a/‸\"\n", "", false, false},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		segments, onLast, ok := cx.ImportPathSegments()
+		if got := strings.Join(segments, "|"); got != c.segments || onLast != c.onLast || ok != c.ok {
+			t.Errorf("ImportPathSegments() = (%q, %v, %v), want (%q, %v, %v) in %q", got, onLast, ok, c.segments, c.onLast, c.ok, c.src)
+		}
+	}
+}
+
+func TestBlankImportContext(t *testing.T) {
+	cases := []struct {
+		src        string
+		hasComment bool
+		ok         bool
+	}{
+		{"import _ \"emb‸ed\"", false, true},
+		{"import (\n\t_ \"net/http/pp‸rof\" // for side effects\n)", true, true},
+		{"import (\n\t// register the driver\n\t‸_ \"github.com/lib/pq\"\n)", true, true},
+		{"import (\n\t_ \"a‸\"\n\t_ \"b\" // b\n)", false, true},
+		{"import \"fm‸t\"", false, false},
+		{"import f \"fm‸t\"", false, false},
+	}
+	for _, c := range cases {
+		src := "package p\n" + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		spec, hasComment, ok := cx.BlankImportContext()
+		if hasComment != c.hasComment || ok != c.ok || (spec != nil) != c.ok {
+			t.Errorf("BlankImportContext() = (%v, %v, %v), want (%v, %v, %v) in %q", spec != nil, hasComment, ok, c.ok, c.hasComment, c.ok, c.src)
+		}
+	}
+}
+
+func TestSuggestImportAlias(t *testing.T) {
+	cases := []struct {
+		src        string
+		path       string
+		alias      string
+		needsAlias bool
+	}{
+		{"", "strings", "strings", false},
+		{"", "github.com/go-chi/chi/v5", "chi", false},
+		{"", "gopkg.in/yaml.v2", "yaml", false},
+		{"", "k8s.io/api/core/v1", "corev1", true},
+		{"", "k8s.io/api/apps/v1beta1", "appsv1beta1", true},
+		{"", "github.com/mattn/go-isatty", "goisatty", true},
+		{"import \"math/rand\"\n", "crypto/rand", "cryptorand", true},
+		{"import \"math/rand\"\n", "math/rand", "rand", false},
+		{"import r \"math/rand\"\n", "math/rand", "r", true},
+		{"import \"errors\"\n", "github.com/pkg/errors", "pkgerrors", true},
+		{"import (\n\t\"errors\"\n\tpkgerrors \"x/errors\"\n)\n", "github.com/pkg/errors", "errors2", true},
+		{"type url struct{}\n", "net/url", "neturl", true},
+		{"func f() {\n\tpath := \"\"\n\t‸\n}\n", "path", "path2", true},
+	}
+	for _, c := range cases {
+		src := "package p\n" + c.src
+		if !strings.Contains(src, cursorMarker) {
+			src += "‸"
+		}
+		cx := newTestCurCtx(t, src)
+		alias, needsAlias := cx.SuggestImportAlias(c.path)
+		if alias != c.alias || needsAlias != c.needsAlias {
+			t.Errorf("SuggestImportAlias(%q) = (%q, %v), want (%q, %v) in %q", c.path, alias, needsAlias, c.alias, c.needsAlias, c.src)
+		}
+	}
+}
+
+func TestKnownStdlibPackage(t *testing.T) {
+	cases := []struct {
+		src  string
+		name string
+		path string
+	}{
+		{"bytes.Buf‸", "bytes", "bytes"},
+		{"http.Get‸", "http", "net/http"},
+		{"rand.Int‸", "rand", "math/rand"},
+		{"template.New‸", "template", "text/template"},
+		{"utf8.‸", "utf8", "unicode/utf8"},
+		{"yaml.‸", "yaml", ""},
+		{"v2.‸", "v2", ""},
+		{"bytes := []byte{}\n\tbytes‸", "bytes", ""},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		path, ok := cx.KnownStdlibPackage(c.name)
+		if path != c.path || ok != (c.path != "") {
+			t.Errorf("KnownStdlibPackage(%q) = (%q, %v), want (%q, %v) in %q", c.name, path, ok, c.path, c.path != "", c.src)
+		}
+	}
+}
+
+func TestUnresolvedPackageSelector(t *testing.T) {
+	cases := []struct {
+		src  string
+		name string
+		sel  string
+		ok   bool
+	}{
+		{"strings.Rep‸", "strings", "Rep", true},
+		{"strings.‸", "strings", "", true},
+		{"x := strings.Rep‸()", "strings", "Rep", true},
+		{"fmt.Print‸", "", "", false},
+		{"s := \"\"\n\ts.Len‸", "", "", false},
+		{"p.‸", "", "", false},
+		{"T.‸", "", "", false},
+		{"strin‸gs.Rep", "", "", false},
+		{"f(‸)", "", "", false},
+	}
+	for _, c := range cases {
+		src := "package p\nimport \"fmt\"\ntype T struct{}\nfunc f(p int) {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		name, sel, ok := cx.UnresolvedPackageSelector()
+		if name != c.name || sel != c.sel || ok != c.ok {
+			t.Errorf("UnresolvedPackageSelector() = (%q, %q, %v), want (%q, %q, %v) in %q", name, sel, ok, c.name, c.sel, c.ok, c.src)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package cursor
+
+import (
+	"bytes"
+	"go/ast"
+	"unicode/utf8"
+)
+
+// CompletionItemKind is the kind of a completion item.
+// The values match CompletionItemKind in the LSP spec's textDocument/completion section,
+// so they can be used as-is.
+type CompletionItemKind int
+
+const (
+	// TextItem is plain text
+	TextItem CompletionItemKind = 1
+
+	// MethodItem is a method e.g. `x.Close` after `x.`
+	MethodItem CompletionItemKind = 2
+
+	// FunctionItem is a function e.g. `fmt.Println` or a builtin like `len`
+	FunctionItem CompletionItemKind = 3
+
+	// ConstructorItem is a constructor; Go has none, it's unused by this package
+	ConstructorItem CompletionItemKind = 4
+
+	// FieldItem is a struct field e.g. `x.Name` after `x.`
+	FieldItem CompletionItemKind = 5
+
+	// VariableItem is a variable or parameter
+	VariableItem CompletionItemKind = 6
+
+	// ClassItem is a class; Go has none, it's unused by this package
+	ClassItem CompletionItemKind = 7
+
+	// InterfaceItem is an interface type
+	InterfaceItem CompletionItemKind = 8
+
+	// ModuleItem is a package e.g. in an import path or before a selector
+	ModuleItem CompletionItemKind = 9
+
+	// PropertyItem is a property; Go has none, it's unused by this package
+	PropertyItem CompletionItemKind = 10
+
+	// UnitItem is a unit of measure; it's unused by this package
+	UnitItem CompletionItemKind = 11
+
+	// ValueItem is a value; it's unused by this package
+	ValueItem CompletionItemKind = 12
+
+	// EnumItem is an enum type; Go has none, it's unused by this package
+	EnumItem CompletionItemKind = 13
+
+	// KeywordItem is a keyword e.g. `return` or `defer`
+	KeywordItem CompletionItemKind = 14
+
+	// SnippetItem is a snippet e.g. a statement template at the start of a statement
+	SnippetItem CompletionItemKind = 15
+
+	// ColorItem is a color; it's unused by this package
+	ColorItem CompletionItemKind = 16
+
+	// FileItem is a file name e.g. in a `//go:embed` pattern
+	FileItem CompletionItemKind = 17
+
+	// ReferenceItem is a reference to another item; it's unused by this package
+	ReferenceItem CompletionItemKind = 18
+
+	// FolderItem is a directory name e.g. in a `//go:embed` pattern
+	FolderItem CompletionItemKind = 19
+
+	// EnumMemberItem is a member of an enum; it's unused by this package
+	EnumMemberItem CompletionItemKind = 20
+
+	// ConstantItem is a constant
+	ConstantItem CompletionItemKind = 21
+
+	// StructItem is a struct or other non-interface named type
+	StructItem CompletionItemKind = 22
+
+	// EventItem is an event; it's unused by this package
+	EventItem CompletionItemKind = 23
+
+	// OperatorItem is an operator; it's unused by this package
+	OperatorItem CompletionItemKind = 24
+
+	// TypeParameterItem is a type parameter e.g. `T` in `func f[T any]()`
+	TypeParameterItem CompletionItemKind = 25
+)
+
+// ExpectedItemKinds returns the kinds of completion items that are plausible at the cursor
+// e.g. FieldItem and MethodItem after `x.`, or ModuleItem in an import path.
+// It returns nil if no completions are expected e.g. in a comment.
+func (cx *CurCtx) ExpectedItemKinds() []CompletionItemKind {
+	switch {
+	case cx.Scope.Is(ImportPathScope):
+		return []CompletionItemKind{ModuleItem}
+	case cx.Scope.Is(EmbedScope):
+		return []CompletionItemKind{FileItem, FolderItem}
+	case cx.Scope.Is(CommentScope, StringScope, PackageScope):
+		return nil
+	}
+
+	var se *ast.SelectorExpr
+	if cx.Set(&se) && cx.TokenPos > se.X.End() {
+		if id, ok := se.X.(*ast.Ident); ok && (cx.importNames()[id.Name] || !cx.isDeclared(id.Name)) {
+			// probably a package
+			return []CompletionItemKind{FunctionItem, VariableItem, ConstantItem, StructItem, InterfaceItem}
+		}
+		return []CompletionItemKind{FieldItem, MethodItem}
+	}
+
+	if cx.ExpectsType() {
+		return []CompletionItemKind{StructItem, InterfaceItem, TypeParameterItem, ModuleItem}
+	}
+
+	switch {
+	case cx.Scope.Is(FileScope, FuncDeclScope, TypeDeclScope):
+		return []CompletionItemKind{KeywordItem, SnippetItem}
+	case cx.Scope.Is(StmtStartScope):
+		return []CompletionItemKind{KeywordItem, SnippetItem, VariableItem, FunctionItem, ModuleItem}
+	}
+	return []CompletionItemKind{VariableItem, ConstantItem, FunctionItem, ModuleItem}
+}
+
+// LSPRange returns the range of n in LSP terms:
+// zero-based lines and characters counted in UTF-16 code units.
+func (cx *CurCtx) LSPRange(n ast.Node) (startLine, startChar, endLine, endChar int) {
+	startLine, startChar = cx.lspPos(cx.TokenFile.Offset(n.Pos()))
+	endLine, endChar = cx.lspPos(cx.TokenFile.Offset(n.End()))
+	return startLine, startChar, endLine, endChar
+}
+
+// lspPos converts the byte offset pos into an LSP line and UTF-16 character
+func (cx *CurCtx) lspPos(pos int) (line, char int) {
+	if pos > len(cx.Src) {
+		pos = len(cx.Src)
+	}
+	ls := lineStart(cx.Src, pos)
+	line = bytes.Count(cx.Src[:ls], []byte{'\n'})
+	for s := cx.Src[ls:pos]; len(s) != 0; {
+		r, n := utf8.DecodeRune(s)
+		s = s[n:]
+		if r >= 0x10000 {
+			// surrogate pair
+			char += 2
+		} else {
+			char++
+		}
+	}
+	return line, char
+}
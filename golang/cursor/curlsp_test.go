@@ -0,0 +1,56 @@
+package cursor
+
+import (
+	"go/ast"
+	"reflect"
+	"testing"
+)
+
+func TestLSPRange(t *testing.T) {
+	cases := []struct {
+		src  string
+		want [4]int
+	}{
+		{"package p\nvar s = \"abc\" + x‸\n", [4]int{1, 16, 1, 17}},
+		{"package p\nvar s = \"é\" + x‸\n", [4]int{1, 14, 1, 15}},
+		{"package p\nvar s = \"😀\" + x‸\n", [4]int{1, 15, 1, 16}},
+		{"package p\nvar s = \"😀\\n\" + \"世界\" + x‸\n", [4]int{1, 24, 1, 25}},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		id, ok := cx.Node.(*ast.Ident)
+		if !ok {
+			t.Fatalf("expected cursor on an identifier, got %T in %q", cx.Node, c.src)
+		}
+		sl, sc, el, ec := cx.LSPRange(id)
+		if got := [4]int{sl, sc, el, ec}; got != c.want {
+			t.Errorf("LSPRange() = %v, want %v in %q", got, c.want, c.src)
+		}
+	}
+}
+
+func TestExpectedItemKinds(t *testing.T) {
+	cases := []struct {
+		src  string
+		want []CompletionItemKind
+	}{
+		{"import \"fm‸t\"", []CompletionItemKind{ModuleItem}},
+		{"//go:embed *.t‸xt\nvar fs embed.FS", []CompletionItemKind{FileItem, FolderItem}},
+		{"// comment‸", nil},
+		{"var s = \"‸\"", nil},
+		{"func f() {\n\tx.‸\n}", []CompletionItemKind{FieldItem, MethodItem}},
+		{"func f() {\n\tstrings.‸\n}", []CompletionItemKind{FunctionItem, VariableItem, ConstantItem, StructItem, InterfaceItem}},
+		{"var y i‸", []CompletionItemKind{StructItem, InterfaceItem, TypeParameterItem, ModuleItem}},
+		{"‸", []CompletionItemKind{KeywordItem, SnippetItem}},
+		{"func f() {\n\t‸\n}", []CompletionItemKind{KeywordItem, SnippetItem, VariableItem, FunctionItem, ModuleItem}},
+		{"func f(a i‸) {}", []CompletionItemKind{StructItem, InterfaceItem, TypeParameterItem, ModuleItem}},
+		{"func f() {\n\ty := ‸\n}", []CompletionItemKind{VariableItem, ConstantItem, FunctionItem, ModuleItem}},
+	}
+	for _, c := range cases {
+		src := "package p\ntype T struct{ A int }\nvar x T\n" + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		if got := cx.ExpectedItemKinds(); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("ExpectedItemKinds() = %v, want %v in %q", got, c.want, c.src)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package cursor
+
+import (
+	"sync"
+)
+
+type curMemo struct {
+	sync.Mutex
+	m map[interface{}]interface{}
+}
+
+// Memo returns the value stored for key, calling compute to populate it on first use.
+//
+// It allows cooperating reducers to share data derived from the CurCtx without recomputing it.
+// Values live as long as the CurCtx, and are shared with the copies of it returned from the cache
+// for the same src and pos, so they must only depend on the CurCtx, not on e.g. the editor state.
+//
+// It's safe for concurrent use. compute is called without holding any locks so it may call Memo,
+// but it may be called more than once if there are concurrent calls for the same key.
+// Keys should be of an unexported type, as with context.Context values.
+func (cx *CurCtx) Memo(key interface{}, compute func() interface{}) interface{} {
+	mm := cx.memo
+	mm.Lock()
+	v, ok := mm.m[key]
+	mm.Unlock()
+	if ok {
+		return v
+	}
+
+	v = compute()
+
+	mm.Lock()
+	defer mm.Unlock()
+	if x, ok := mm.m[key]; ok {
+		return x
+	}
+	mm.m[key] = v
+	return v
+}
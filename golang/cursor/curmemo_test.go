@@ -0,0 +1,39 @@
+package cursor
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestMemo(t *testing.T) {
+	type key struct{ s string }
+	cx := newTestCurCtx(t, "package p\nfunc f() {\n\t‸\n}\n")
+
+	calls := 0
+	compute := func() interface{} {
+		calls++
+		return calls
+	}
+	if v := cx.Memo(key{"a"}, compute); v != 1 {
+		t.Errorf("Memo(a) = %v, want 1", v)
+	}
+	if v := cx.Memo(key{"a"}, compute); v != 1 || calls != 1 {
+		t.Errorf("Memo(a) = %v after %d calls, want 1 after 1 call", v, calls)
+	}
+	if v := cx.Memo(key{"b"}, compute); v != 2 {
+		t.Errorf("Memo(b) = %v, want 2", v)
+	}
+	if v := cx.Memo(key{"c"}, func() interface{} { return cx.Memo(key{"a"}, compute) }); v != 1 {
+		t.Errorf("nested Memo(a) = %v, want 1", v)
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cx.Memo(key{"d"}, func() interface{} { return 0 })
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,434 @@
+package cursor
+
+import (
+	"go/ast"
+	"go/token"
+	"margo.sh/golang/goutil"
+)
+
+// localDecl describes a name declared inside a function
+type localDecl struct {
+	// Name is the declared identifier
+	Name *ast.Ident
+
+	// Tok is token.TYPE for types and type params, token.CONST for constants
+	// and token.VAR for everything else
+	Tok token.Token
+
+	// Type is the declared type, if any
+	Type ast.Expr
+
+	// Value is the value the name is initialised with, if known
+	Value ast.Expr
+}
+
+// DeclaredNames returns the names of the local variables, constants, types and params
+// that are in scope at the cursor.
+//
+// Only declarations inside functions are included, in the order they're declared.
+// The blank identifier `_` is never included.
+func (cx *CurCtx) DeclaredNames() []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, d := range cx.localDecls() {
+		if nm := d.Name.Name; !seen[nm] {
+			seen[nm] = true
+			names = append(names, nm)
+		}
+	}
+	return names
+}
+
+// isDeclared returns true if name is declared locally or at the top-level of the file
+func (cx *CurCtx) isDeclared(name string) bool {
+	for _, d := range cx.localDecls() {
+		if d.Name.Name == name {
+			return true
+		}
+	}
+	return cx.topLevelNames()[name]
+}
+
+// localDecls returns the declarations inside functions that are in scope at the cursor,
+// in the order they're declared. Later declarations shadow earlier ones.
+func (cx *CurCtx) localDecls() []localDecl {
+	decls := []localDecl{}
+	add := func(d localDecl) {
+		if d.Name != nil && d.Name.Name != "_" {
+			decls = append(decls, d)
+		}
+	}
+	addFields := func(tok token.Token, fls ...*ast.FieldList) {
+		for _, fl := range fls {
+			if fl == nil {
+				continue
+			}
+			for _, f := range fl.List {
+				for _, id := range f.Names {
+					add(localDecl{Name: id, Tok: tok, Type: f.Type})
+				}
+			}
+		}
+	}
+	addStmts := func(l []ast.Stmt) {
+		for _, s := range l {
+			if s.End() >= cx.TokenPos || goutil.NodeEnclosesPos(s, cx.TokenPos) {
+				break
+			}
+			for _, d := range stmtDecls(s) {
+				add(d)
+			}
+		}
+	}
+
+	for _, n := range cx.Nodes {
+		switch x := n.(type) {
+		case *ast.FuncDecl:
+			addFields(token.VAR, x.Recv)
+			for _, id := range receiverTypeParams(x) {
+				add(localDecl{Name: id, Tok: token.TYPE})
+			}
+			addFields(token.TYPE, x.Type.TypeParams)
+			addFields(token.VAR, x.Type.Params, x.Type.Results)
+		case *ast.FuncLit:
+			addFields(token.VAR, x.Type.Params, x.Type.Results)
+		case *ast.IfStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.ForStmt:
+			for _, d := range cx.controlInitDecls(x) {
+				add(d)
+			}
+			// the type switch binding is declared in each clause
+			if ts, ok := x.(*ast.TypeSwitchStmt); ok && ts.Assign != nil && cx.clauseAt(ts.Body) != nil {
+				for _, d := range stmtDecls(ts.Assign) {
+					add(d)
+				}
+			}
+		case *ast.RangeStmt:
+			if x.Tok == token.DEFINE && x.Body != nil && x.Body.Lbrace < cx.TokenPos {
+				for _, e := range []ast.Expr{x.Key, x.Value} {
+					if id, ok := e.(*ast.Ident); ok {
+						add(localDecl{Name: id, Tok: token.VAR})
+					}
+				}
+			}
+		case *ast.BlockStmt:
+			addStmts(x.List)
+			switch c := cx.clauseAt(x).(type) {
+			case *ast.CaseClause:
+				addStmts(c.Body)
+			case *ast.CommClause:
+				if c.Comm != nil {
+					for _, d := range stmtDecls(c.Comm) {
+						add(d)
+					}
+				}
+				addStmts(c.Body)
+			}
+		}
+	}
+	return decls
+}
+
+// clauseAt returns the case or comm clause in the switch or select body whose body the cursor is in.
+// A clause's body extends to the next clause, but its range ends at its last statement,
+// so the clause may not be in cx.Nodes.
+func (cx *CurCtx) clauseAt(body *ast.BlockStmt) ast.Stmt {
+	if body == nil {
+		return nil
+	}
+	var clause ast.Stmt
+	for _, s := range body.List {
+		switch x := s.(type) {
+		case *ast.CaseClause:
+			if x.Colon < cx.TokenPos {
+				clause = x
+			}
+		case *ast.CommClause:
+			if x.Colon < cx.TokenPos {
+				clause = x
+			}
+		}
+	}
+	if clause == nil || cx.TokenPos > body.Rbrace {
+		return nil
+	}
+	return clause
+}
+
+// ControlInitVars returns the names declared in the init statements of the if, switch and for statements
+// enclosing the cursor e.g. `[v ok]` in `if v, ok := m[k]; ok { ‸ }`.
+// Names are only in scope after the init statement, so the names being declared are not included.
+func (cx *CurCtx) ControlInitVars() []string {
+	names := []string{}
+	for _, n := range cx.Nodes {
+		for _, d := range cx.controlInitDecls(n) {
+			if nm := d.Name.Name; nm != "_" {
+				names = append(names, nm)
+			}
+		}
+	}
+	return names
+}
+
+// controlInitDecls returns the declarations made by the init statement of the if, switch or for statement n
+// that are in scope at the cursor
+func (cx *CurCtx) controlInitDecls(n ast.Node) []localDecl {
+	var init ast.Stmt
+	switch x := n.(type) {
+	case *ast.IfStmt:
+		init = x.Init
+	case *ast.SwitchStmt:
+		init = x.Init
+	case *ast.TypeSwitchStmt:
+		init = x.Init
+	case *ast.ForStmt:
+		init = x.Init
+	}
+	if init == nil || cx.TokenPos <= init.End() {
+		return nil
+	}
+	return stmtDecls(init)
+}
+
+// stmtDecls returns the declarations made by the statement s
+func stmtDecls(s ast.Stmt) []localDecl {
+	switch x := s.(type) {
+	case *ast.AssignStmt:
+		if x.Tok != token.DEFINE {
+			return nil
+		}
+		l := []localDecl{}
+		for i, e := range x.Lhs {
+			id, ok := e.(*ast.Ident)
+			if !ok {
+				continue
+			}
+			d := localDecl{Name: id, Tok: token.VAR}
+			if len(x.Lhs) == len(x.Rhs) {
+				d.Value = x.Rhs[i]
+			}
+			l = append(l, d)
+		}
+		return l
+	case *ast.DeclStmt:
+		if gd, ok := x.Decl.(*ast.GenDecl); ok {
+			return genDeclDecls(gd)
+		}
+	}
+	return nil
+}
+
+// genDeclDecls returns the declarations made by the specs in gd
+func genDeclDecls(gd *ast.GenDecl) []localDecl {
+	l := []localDecl{}
+	for _, spec := range gd.Specs {
+		switch x := spec.(type) {
+		case *ast.ValueSpec:
+			for i, id := range x.Names {
+				d := localDecl{Name: id, Tok: gd.Tok, Type: x.Type}
+				if len(x.Names) == len(x.Values) {
+					d.Value = x.Values[i]
+				}
+				l = append(l, d)
+			}
+		case *ast.TypeSpec:
+			l = append(l, localDecl{Name: x.Name, Tok: token.TYPE, Type: x.Type})
+		}
+	}
+	return l
+}
+
+// topLevelNames returns the set of names declared at the top-level of the file
+func (cx *CurCtx) topLevelNames() map[string]bool {
+	names := map[string]bool{}
+	if cx.AstFile == nil {
+		return names
+	}
+	for _, d := range cx.AstFile.Decls {
+		switch x := d.(type) {
+		case *ast.FuncDecl:
+			if x.Recv == nil && x.Name != nil {
+				names[x.Name.Name] = true
+			}
+		case *ast.GenDecl:
+			for _, d := range genDeclDecls(x) {
+				names[d.Name.Name] = true
+			}
+		}
+	}
+	return names
+}
+
+// varType returns the syntactic type of the variable name that's in scope at the cursor.
+// Local declarations are searched first, then the top-level declarations of the file.
+func (cx *CurCtx) varType(name string) (ast.Expr, bool) {
+	decls := cx.localDecls()
+	for i := len(decls) - 1; i >= 0; i-- {
+		if d := decls[i]; d.Name.Name == name {
+			if d.Tok == token.TYPE {
+				return nil, false
+			}
+			return declType(d)
+		}
+	}
+	if cx.AstFile == nil {
+		return nil, false
+	}
+	for _, decl := range cx.AstFile.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.VAR {
+			continue
+		}
+		for _, d := range genDeclDecls(gd) {
+			if d.Name.Name == name {
+				return declType(d)
+			}
+		}
+	}
+	return nil, false
+}
+
+// typeDecl returns the type expression of the type name declared in the file that's in scope at the cursor
+// e.g. `struct{...}` for `type T struct{...}`.
+func (cx *CurCtx) typeDecl(name string) (ast.Expr, bool) {
+	decls := cx.localDecls()
+	for i := len(decls) - 1; i >= 0; i-- {
+		if d := decls[i]; d.Name.Name == name {
+			return d.Type, d.Tok == token.TYPE && d.Type != nil
+		}
+	}
+	if cx.AstFile == nil {
+		return nil, false
+	}
+	for _, decl := range cx.AstFile.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, d := range genDeclDecls(gd) {
+			if d.Name.Name == name {
+				return d.Type, d.Type != nil
+			}
+		}
+	}
+	return nil, false
+}
+
+// declType returns the declared type of d or the type inferred from its value
+func declType(d localDecl) (ast.Expr, bool) {
+	if d.Type != nil {
+		return d.Type, true
+	}
+	typ := exprType(d.Value)
+	return typ, typ != nil
+}
+
+// exprType returns the type of x if it can be trivially determined syntactically
+// e.g. `T{}`, `&T{}`, `new(T)` and `func() {}`
+func exprType(x ast.Expr) ast.Expr {
+	switch x := x.(type) {
+	case *ast.ParenExpr:
+		return exprType(x.X)
+	case *ast.CompositeLit:
+		return x.Type
+	case *ast.FuncLit:
+		return x.Type
+	case *ast.UnaryExpr:
+		if x.Op != token.AND {
+			return nil
+		}
+		if typ := exprType(x.X); typ != nil {
+			return &ast.StarExpr{Star: x.OpPos, X: typ}
+		}
+	case *ast.CallExpr:
+		if id, ok := x.Fun.(*ast.Ident); ok && id.Name == "new" && len(x.Args) == 1 {
+			return &ast.StarExpr{Star: x.Fun.Pos(), X: x.Args[0]}
+		}
+	}
+	return nil
+}
+
+// BuiltinsAllowed returns false if the cursor is in a position where builtin functions and types
+// are nonsensical e.g. the member position of a selector `x.‸`, a struct field name, the name of a type declaration,
+// an import path, a string or comment.
+func (cx *CurCtx) BuiltinsAllowed() bool {
+	if cx.Scope.Is(PackageScope, ImportScope, StringScope, CommentScope) {
+		return false
+	}
+	var se *ast.SelectorExpr
+	if cx.Set(&se) && cx.TokenPos > se.X.End() {
+		return false
+	}
+	var ts *ast.TypeSpec
+	if cx.Set(&ts) && goutil.NodeEnclosesPos(ts.Name, cx.TokenPos) {
+		return false
+	}
+	var st *ast.StructType
+	if !cx.Set(&st) || st.Fields == nil {
+		return true
+	}
+	fl := st.Fields
+	if cx.TokenPos <= fl.Opening || cx.TokenPos > fl.Closing {
+		return true
+	}
+	for _, f := range fl.List {
+		if !goutil.NodeEnclosesPos(f, cx.TokenPos) {
+			continue
+		}
+		if n := len(f.Names); n != 0 {
+			// we're in the type if we're after the last name
+			return cx.TokenPos > f.Names[n-1].End()
+		}
+		// an embedded type is indistinguishable from a field name being typed
+		return false
+	}
+	return false
+}
+
+// VarIsUnused returns the name of the local variable declared by the identifier at the cursor
+// e.g. `x` in `x‸ := f()`, iff it's never used in its scope.
+//
+// Assigning to a variable doesn't count as a use, and uses of other variables
+// that shadow it are not counted. Params and the blank identifier are never reported.
+func (cx *CurCtx) VarIsUnused() (name string, ok bool) {
+	id, _ := cx.Node.(*ast.Ident)
+	if id == nil || id.Name == "_" || id.Obj == nil || id.Obj.Kind != ast.Var || id.Obj.Pos() != id.Pos() {
+		return "", false
+	}
+	switch id.Obj.Decl.(type) {
+	case *ast.AssignStmt, *ast.ValueSpec:
+	default:
+		return "", false
+	}
+	_, body := funcTypeBody(cx.enclosingFunc())
+	if body == nil || !goutil.NodeEnclosesPos(body, id.Pos()) {
+		return "", false
+	}
+
+	assigned := map[*ast.Ident]bool{}
+	used := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if used {
+			return false
+		}
+		switch x := n.(type) {
+		case *ast.AssignStmt:
+			// this includes variables redeclared by `:=` e.g. `err` in `a, err := f(); b, err := g()`
+			for _, e := range x.Lhs {
+				if id, ok := e.(*ast.Ident); ok {
+					assigned[id] = true
+				}
+			}
+		case *ast.IncDecStmt:
+			if id, ok := x.X.(*ast.Ident); ok {
+				assigned[id] = true
+			}
+		case *ast.Ident:
+			used = x != id && x.Obj == id.Obj && !assigned[x]
+		}
+		return true
+	})
+	if used {
+		return "", false
+	}
+	return id.Name, true
+}
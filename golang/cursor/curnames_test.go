@@ -0,0 +1,116 @@
+package cursor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestControlInitVars(t *testing.T) {
+	cases := []struct {
+		src      string
+		initVars string
+		declared string
+	}{
+		{"if v, ok := m[k]; ok {\n\t\t‸\n\t}", "v|ok", "m|v|ok"},
+		{"if v, ok := m[k]; ‸ok {\n\t}", "v|ok", "m|v|ok"},
+		{"if v, ok := m[‸k]; ok {\n\t}", "", "m"},
+		{"switch n := len(m); n {\n\tcase 1:\n\t\t‸\n\t}", "n", "m|n"},
+		{"for i := 0; i < 3; i++ {\n\t\t‸\n\t}", "i", "m|i"},
+		{"if _, ok := m[k]; ok {\n\t\tfor j := 0; ; {\n\t\t\t‸\n\t\t}\n\t}", "ok|j", "m|ok|j"},
+		{"if x := 1; x > 0 {\n\t}\n\t‸", "", "m"},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\tm := map[string]int{}\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		if got := strings.Join(cx.ControlInitVars(), "|"); got != c.initVars {
+			t.Errorf("ControlInitVars() = %q, want %q in %q", got, c.initVars, c.src)
+		}
+		if got := strings.Join(cx.DeclaredNames(), "|"); got != c.declared {
+			t.Errorf("DeclaredNames() = %q, want %q in %q", got, c.declared, c.src)
+		}
+	}
+}
+
+func TestDeclaredNamesInClauses(t *testing.T) {
+	cases := []struct {
+		src      string
+		declared string
+	}{
+		{"switch n := len(m); n {\n\tcase 1:\n\t\tx := n\n\t\t‸\n\t}", "m|n|x"},
+		{"switch n := len(m); n {\n\tcase 1:\n\t\tx := n\n\tcase 2:\n\t\t‸\n\t}", "m|n"},
+		{"switch v := any(m).(type) {\n\tcase int:\n\t\t‸\n\t}", "m|v"},
+		{"switch v := any(m).(type) {\n\tcase int:\n\t\ty := v\n\t\t_ = ‸y\n\t}", "m|v|y"},
+		{"switch v := any(‸m).(type) {\n\tcase int:\n\t}", "m"},
+		{"select {\n\tcase v, ok := <-ch:\n\t\t‸\n\t}", "m|v|ok"},
+		{"select {\n\tcase v := <-ch:\n\t\tw := v\n\t\t‸\n\tdefault:\n\t}", "m|v|w"},
+		{"select {\n\tcase v := <-ch:\n\tdefault:\n\t\t‸\n\t}", "m"},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\tm := map[string]int{}\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		if got := strings.Join(cx.DeclaredNames(), "|"); got != c.declared {
+			t.Errorf("DeclaredNames() = %q, want %q in %q", got, c.declared, c.src)
+		}
+	}
+}
+
+func TestVarIsUnused(t *testing.T) {
+	cases := []struct {
+		src  string
+		name string
+	}{
+		{"x‸ := g()", "x"},
+		{"var x‸ int", "x"},
+		{"x‸ := g()\n\t_ = x", ""},
+		{"x‸ := g()\n\tx = 2", "x"},
+		{"x‸ := g()\n\tx++", "x"},
+		{"x‸ := g()\n\tx += 1", "x"},
+		{"x‸ := g()\n\tx += x", ""},
+		{"x‸ := g()\n\tif true {\n\t\tx := 1\n\t\t_ = x\n\t}", "x"},
+		{"x‸ := g()\n\tif true {\n\t\t_ = x\n\t}", ""},
+		{"a, err‸ := g2()\n\tb, err := g2()\n\t_, _ = a, b", "err"},
+		{"a, err‸ := g2()\n\tb, err := g2()\n\t_, _, _ = a, b, err", ""},
+		{"x‸ := g()\n\tfunc() {\n\t\t_ = x\n\t}()", ""},
+		{"x‸ := g()\n\tfunc() {\n\t\tx = 1\n\t}()", "x"},
+		{"_‸ = g()", ""},
+		{"p‸ = 1", ""},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc g() int { return 0 }\nfunc g2() (int, error) { return 0, nil }\nfunc f(p int) {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		name, ok := cx.VarIsUnused()
+		if name != c.name || ok != (c.name != "") {
+			t.Errorf("VarIsUnused() = (%q, %v), want (%q, %v) in %q", name, ok, c.name, c.name != "", c.src)
+		}
+	}
+}
+
+func TestBuiltinsAllowed(t *testing.T) {
+	cases := []struct {
+		src  string
+		want bool
+	}{
+		{"func f() {\n\t‸\n}", true},
+		{"func f() {\n\tle‸\n}", true},
+		{"func f() {\n\tx := le‸\n}", true},
+		{"func f() {\n\tg(le‸)\n}", true},
+		{"var x in‸", true},
+		{"type T struct {\n\tA in‸\n}", true},
+		{"func f() {\n\tx.le‸\n}", false},
+		{"func f() {\n\tx.‸\n}", false},
+		{"import \"fm‸t\"", false},
+		{"import (\n\t‸\n)", false},
+		{"type T‸ int", false},
+		{"type (\n\tT‸ int\n)", false},
+		{"type T struct {\n\tle‸\n}", false},
+		{"func f() {\n\ts := \"le‸\"\n}", false},
+		{"// le‸", false},
+	}
+	for _, c := range cases {
+		src := "package p\n" + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		if got := cx.BuiltinsAllowed(); got != c.want {
+			t.Errorf("BuiltinsAllowed() = %v, want %v in %q", got, c.want, c.src)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package cursor
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// RangeKind describes the kind of value a range statement iterates over
+type RangeKind uint8
+
+const (
+	// UnknownRange is the zero value, the kind of the range expression couldn't be determined
+	UnknownRange RangeKind = iota
+
+	// SliceRange is a range over a slice, array or pointer to an array e.g. `range []int{}`
+	SliceRange
+
+	// MapRange is a range over a map e.g. `range map[string]int{}`
+	MapRange
+
+	// StringRange is a range over a string e.g. `range "abc"`
+	StringRange
+
+	// ChanRange is a range over a channel e.g. `range make(chan int)`
+	ChanRange
+
+	// IntRange is a range over an integer e.g. `range 10` (Go 1.22)
+	IntRange
+
+	// FuncRange is a range over an iterator function e.g. `range seq` (Go 1.23)
+	FuncRange
+)
+
+// RangeVars returns the names of the iteration variables of the range statement whose body encloses the cursor
+// e.g. `[i v]` in `for i, v := range l { ‸ }` or `[i]` in `for i := range 10 { ‸ }`.
+// The blank identifier `_` is not included.
+//
+// If ok is true, the cursor is in RangeScope.
+func (cx *CurCtx) RangeVars() (names []string, ok bool) {
+	rs := cx.enclosingRangeBody()
+	if rs == nil {
+		return nil, false
+	}
+	names = []string{}
+	for _, x := range []ast.Expr{rs.Key, rs.Value} {
+		if id, ok := x.(*ast.Ident); ok && id.Name != "_" {
+			names = append(names, id.Name)
+		}
+	}
+	return names, true
+}
+
+// RangeKind returns the kind of the range expression of the range statement whose body encloses the cursor.
+//
+// The kind is determined syntactically from the range expression: literals, composite literals,
+// conversions, calls to `make`, `len` and `cap`, func literals, and variables whose types are declared in the file.
+// ok is false if the cursor is not in a range body, or the kind couldn't be determined.
+func (cx *CurCtx) RangeKind() (RangeKind, bool) {
+	rs := cx.enclosingRangeBody()
+	if rs == nil {
+		return UnknownRange, false
+	}
+	k := cx.rangeExprKind(rs.X)
+	return k, k != UnknownRange
+}
+
+// enclosingRangeBody returns the innermost range statement whose body encloses the cursor
+func (cx *CurCtx) enclosingRangeBody() *ast.RangeStmt {
+	for i := len(cx.Nodes) - 1; i >= 0; i-- {
+		switch x := cx.Nodes[i].(type) {
+		case *ast.RangeStmt:
+			if x.Body != nil && x.Body.Lbrace < cx.TokenPos && cx.TokenPos <= x.Body.Rbrace {
+				return x
+			}
+		case *ast.FuncLit:
+			return nil
+		}
+	}
+	return nil
+}
+
+// rangeExprKind returns the RangeKind of the range expression x
+func (cx *CurCtx) rangeExprKind(x ast.Expr) RangeKind {
+	switch x := x.(type) {
+	case *ast.BasicLit:
+		switch x.Kind {
+		case token.INT, token.CHAR:
+			return IntRange
+		case token.STRING:
+			return StringRange
+		}
+	case *ast.ParenExpr:
+		return cx.rangeExprKind(x.X)
+	case *ast.FuncLit:
+		return FuncRange
+	case *ast.Ident:
+		if typ, ok := cx.varType(x.Name); ok {
+			return cx.typeRangeKind(typ)
+		}
+	case *ast.CallExpr:
+		fun, _ := x.Fun.(*ast.Ident)
+		switch {
+		case fun != nil && (fun.Name == "len" || fun.Name == "cap"):
+			return IntRange
+		case fun != nil && fun.Name == "make" && len(x.Args) != 0:
+			return cx.typeRangeKind(x.Args[0])
+		case len(x.Args) == 1 && isTypeExpr(x.Fun):
+			return cx.typeRangeKind(x.Fun)
+		case fun != nil && len(x.Args) == 1:
+			if yes, known := cx.identIsType(fun.Name); yes && known {
+				return cx.typeRangeKind(fun)
+			}
+		}
+	}
+	if typ := exprType(x); typ != nil {
+		return cx.typeRangeKind(typ)
+	}
+	return UnknownRange
+}
+
+// typeRangeKind returns the RangeKind of values of type typ
+func (cx *CurCtx) typeRangeKind(typ ast.Expr) RangeKind {
+	// limit the depth to guard against cycles like `type A B; type B A`
+	for i := 0; i < 10; i++ {
+		switch x := typ.(type) {
+		case *ast.Ident:
+			switch x.Name {
+			case "int", "int8", "int16", "int32", "int64",
+				"uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
+				"byte", "rune":
+				return IntRange
+			case "string":
+				return StringRange
+			}
+			t, ok := cx.typeDecl(x.Name)
+			if !ok {
+				return UnknownRange
+			}
+			typ = t
+		case *ast.ParenExpr:
+			typ = x.X
+		case *ast.StarExpr:
+			if _, ok := x.X.(*ast.ArrayType); !ok {
+				return UnknownRange
+			}
+			return SliceRange
+		case *ast.ArrayType:
+			return SliceRange
+		case *ast.MapType:
+			return MapRange
+		case *ast.ChanType:
+			return ChanRange
+		case *ast.FuncType:
+			return FuncRange
+		default:
+			return UnknownRange
+		}
+	}
+	return UnknownRange
+}
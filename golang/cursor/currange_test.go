@@ -0,0 +1,52 @@
+package cursor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRangeKind(t *testing.T) {
+	decls := "package p\ntype L []int\ntype N int\ntype Seq func(yield func(int) bool)\nvar s Seq\n"
+	cases := []struct {
+		src  string
+		vars string
+		kind RangeKind
+	}{
+		{"for i := range 10 {\n\t\t‸\n\t}", "i", IntRange},
+		{"for i := range len(l) {\n\t\t‸\n\t}", "i", IntRange},
+		{"var n N\n\tfor i := range n {\n\t\t‸\n\t}", "i", IntRange},
+		{"for i := range N(3) {\n\t\t‸\n\t}", "i", IntRange},
+		{"for i, v := range []int{1} {\n\t\t‸\n\t}", "i|v", SliceRange},
+		{"var l L\n\tfor _, v := range l {\n\t\t‸\n\t}", "v", SliceRange},
+		{"var a *[4]int\n\tfor i := range a {\n\t\t‸\n\t}", "i", SliceRange},
+		{"m := map[string]int{}\n\tfor k, v := range m {\n\t\t‸\n\t}", "k|v", MapRange},
+		{"for i, r := range \"abc\" {\n\t\t‸\n\t}", "i|r", StringRange},
+		{"var str string\n\tfor _, r := range str {\n\t\t‸\n\t}", "r", StringRange},
+		{"for v := range make(chan int) {\n\t\t‸\n\t}", "v", ChanRange},
+		{"var ch <-chan int\n\tfor v := range ch {\n\t\t‸\n\t}", "v", ChanRange},
+		{"for v := range s {\n\t\t‸\n\t}", "v", FuncRange},
+		{"for v := range func(yield func(int) bool) {} {\n\t\t‸\n\t}", "v", FuncRange},
+		{"for k, v := range unknown {\n\t\t‸\n\t}", "k|v", UnknownRange},
+		{"for range 3 {\n\t\t‸\n\t}", "", IntRange},
+	}
+	for _, c := range cases {
+		src := decls + "func f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		if !cx.Scope.Is(RangeScope) {
+			t.Errorf("Scope = %v, want RangeScope in %q", cx.Scope, c.src)
+		}
+		names, _ := cx.RangeVars()
+		if got := strings.Join(names, "|"); got != c.vars {
+			t.Errorf("RangeVars() = %q, want %q in %q", got, c.vars, c.src)
+		}
+		kind, ok := cx.RangeKind()
+		if kind != c.kind || ok != (c.kind != UnknownRange) {
+			t.Errorf("RangeKind() = (%v, %v), want (%v, %v) in %q", kind, ok, c.kind, c.kind != UnknownRange, c.src)
+		}
+	}
+
+	cx := newTestCurCtx(t, "package p\nfunc f() {\n\tfor i := range 10‸ {\n\t}\n}\n")
+	if _, ok := cx.RangeVars(); ok || cx.Scope.Is(RangeScope) {
+		t.Errorf("RangeVars() = ok, want !ok in the range header")
+	}
+}
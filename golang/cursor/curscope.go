@@ -3,68 +3,138 @@ package cursor
 import (
 	"sort"
 	"strings"
+	"sync"
 )
 
 const (
 	curScopesStart CurScope = 1 << iota
 	AssignmentScope
 	BlockScope
+	ChanScope
 	CommentScope
+	CompositeLitScope
 	ConstScope
+	ConstraintScope
 	DeferScope
 	DocScope
+	EmbedScope
 	ExprScope
 	FileScope
+	FormatStringScope
 	FuncDeclScope
+	FuncLitScope
+	GoScope
 	IdentScope
 	ImportPathScope
 	ImportScope
+	InterfaceBodyScope
 	PackageScope
+	RangeScope
 	ReturnScope
 	SelectorScope
+	StmtStartScope
 	StringScope
 	TypeDeclScope
+	TypeParamScope
 	VarScope
 	curScopesEnd
 )
 
 var (
+	customScopes = struct {
+		sync.RWMutex
+		end   CurScope
+		full  bool
+		names map[CurScope]string
+	}{end: curScopesEnd, names: map[CurScope]string{}}
+
 	scopeNames = map[CurScope]string{
-		AssignmentScope: "AssignmentScope",
-		BlockScope:      "BlockScope",
-		CommentScope:    "CommentScope",
-		ConstScope:      "ConstScope",
-		DeferScope:      "DeferScope",
-		DocScope:        "DocScope",
-		ExprScope:       "ExprScope",
-		FileScope:       "FileScope",
-		FuncDeclScope:   "FuncDeclScope",
-		IdentScope:      "IdentScope",
-		ImportPathScope: "ImportPathScope",
-		ImportScope:     "ImportScope",
-		PackageScope:    "PackageScope",
-		ReturnScope:     "ReturnScope",
-		SelectorScope:   "SelectorScope",
-		StringScope:     "StringScope",
-		TypeDeclScope:   "TypeDeclScope",
-		VarScope:        "VarScope",
+		AssignmentScope:    "AssignmentScope",
+		BlockScope:         "BlockScope",
+		ChanScope:          "ChanScope",
+		CommentScope:       "CommentScope",
+		CompositeLitScope:  "CompositeLitScope",
+		ConstScope:         "ConstScope",
+		ConstraintScope:    "ConstraintScope",
+		DeferScope:         "DeferScope",
+		DocScope:           "DocScope",
+		EmbedScope:         "EmbedScope",
+		ExprScope:          "ExprScope",
+		FileScope:          "FileScope",
+		FormatStringScope:  "FormatStringScope",
+		FuncDeclScope:      "FuncDeclScope",
+		FuncLitScope:       "FuncLitScope",
+		GoScope:            "GoScope",
+		IdentScope:         "IdentScope",
+		ImportPathScope:    "ImportPathScope",
+		ImportScope:        "ImportScope",
+		InterfaceBodyScope: "InterfaceBodyScope",
+		PackageScope:       "PackageScope",
+		RangeScope:         "RangeScope",
+		ReturnScope:        "ReturnScope",
+		SelectorScope:      "SelectorScope",
+		StmtStartScope:     "StmtStartScope",
+		StringScope:        "StringScope",
+		TypeDeclScope:      "TypeDeclScope",
+		TypeParamScope:     "TypeParamScope",
+		VarScope:           "VarScope",
 	}
 )
 
 type CurScope uint64
 
 func (cs CurScope) String() string {
-	if cs <= curScopesStart || cs >= curScopesEnd {
+	customScopes.RLock()
+	end, full := customScopes.end, customScopes.full
+	customScopes.RUnlock()
+
+	if cs <= curScopesStart || !full && cs >= end {
 		return "UnknownCursorScope"
 	}
+	return strings.Join(cs.Names(), "|")
+}
+
+// Names returns the sorted names of the scopes in cs
+func (cs CurScope) Names() []string {
 	l := []string{}
 	for scope, name := range scopeNames {
 		if cs.Is(scope) {
 			l = append(l, name)
 		}
 	}
+
+	customScopes.RLock()
+	for scope, name := range customScopes.names {
+		if cs.Is(scope) {
+			l = append(l, name)
+		}
+	}
+	customScopes.RUnlock()
+
 	sort.Strings(l)
-	return strings.Join(l, "|")
+	return l
+}
+
+// RegisterScope allocates a new scope named name, for use by e.g. string context matchers.
+// It panics if there are no more scope bits available.
+//
+// It's intended to be called during init, and the returned value stored in a package-level var.
+func RegisterScope(name string) CurScope {
+	customScopes.Lock()
+	defer customScopes.Unlock()
+
+	if customScopes.full {
+		panic("cursor.RegisterScope: no more scope bits available for " + name)
+	}
+	// end stays at the last bit once it's handed out, so String keeps working
+	cs := customScopes.end
+	if cs<<1 == 0 {
+		customScopes.full = true
+	} else {
+		customScopes.end <<= 1
+	}
+	customScopes.names[cs] = name
+	return cs
 }
 
 func (cs CurScope) Is(scopes ...CurScope) bool {
@@ -0,0 +1,158 @@
+package cursor
+
+import (
+	"go/ast"
+	"margo.sh/golang/goutil"
+)
+
+// SelectorBase returns the expression before the dot of the selector at the cursor
+// e.g. `x` in `x.‸` or `foo.Bar()` in `foo.Bar().B‸`.
+// ok is false if the cursor is not after the dot.
+func (cx *CurCtx) SelectorBase() (base ast.Expr, ok bool) {
+	var se *ast.SelectorExpr
+	if !cx.Set(&se) || cx.srcPos <= cx.TokenFile.Offset(se.X.End()) {
+		return nil, false
+	}
+	return se.X, true
+}
+
+// SelectorChainText returns the printed selector chain at the cursor
+// e.g. `pkg.Type.Method` in `pkg.Type.Meth‸od.Other` or `foo.Bar().` in `foo.Bar().‸`.
+// An incomplete trailing member is printed as an empty name after the dot.
+func (cx *CurCtx) SelectorChainText() (string, bool) {
+	base, ok := cx.SelectorBase()
+	if !ok {
+		return "", false
+	}
+	var se *ast.SelectorExpr
+	cx.Set(&se)
+	s, err := cx.Print(base)
+	if err != nil {
+		return "", false
+	}
+	if nm := se.Sel.Name; nm != "_" {
+		return s + "." + nm, true
+	}
+	return s + ".", true
+}
+
+// MethodExprReceiverContext returns true if the cursor is on the receiver type of a method expression
+// e.g. `T‸.Method` or `(*T‸).Method`, so type names should be offered.
+//
+// `T.Method` is only distinguished from a selector on a value if `T` is known to be a type,
+// i.e. it's declared in the file or predeclared.
+// `(*x).Method` is treated as a method expression unless `x` is known not to be a type.
+func (cx *CurCtx) MethodExprReceiverContext() (ok bool) {
+	for i := len(cx.Nodes) - 1; i >= 0; i-- {
+		se, _ := cx.Nodes[i].(*ast.SelectorExpr)
+		if se == nil {
+			continue
+		}
+		if !goutil.NodeEnclosesPos(se.X, cx.TokenPos) {
+			return false
+		}
+		typ := se.X
+		if x, ok := typ.(*ast.ParenExpr); ok {
+			typ = x.X
+		}
+		star := false
+		if x, ok := typ.(*ast.StarExpr); ok {
+			typ, star = x.X, true
+		}
+		id := typeNameIdent(typ)
+		if id == nil {
+			return false
+		}
+		yes, known := cx.identIsType(id.Name)
+		if star {
+			return yes || !known
+		}
+		return yes && known
+	}
+	return false
+}
+
+// SelectorBaseType returns the name of the type of the selector base at the cursor
+// e.g. `T` in `x.‸` where `x` is declared as `var x T`, `x := T{}`, `x := &T{}` or `x := new(T)`.
+// isPointer is true if the base is a pointer to the type.
+//
+// The type is inferred syntactically from the declaration of the base, so
+// ok is false if the base isn't a variable declared in the file or the type is in another package.
+func (cx *CurCtx) SelectorBaseType() (typeName string, isPointer bool, ok bool) {
+	base, _ := cx.SelectorBase()
+	id, _ := base.(*ast.Ident)
+	if id == nil {
+		return "", false, false
+	}
+	typ, ok := cx.varType(id.Name)
+	if !ok {
+		return "", false, false
+	}
+	if x, ok := typ.(*ast.StarExpr); ok {
+		typ, isPointer = x.X, true
+	}
+	if id := typeNameIdent(typ); id != nil {
+		return id.Name, isPointer, true
+	}
+	return "", false, false
+}
+
+// PromotedMembersSources returns the types embedded in the struct type of the selector base at the cursor
+// e.g. `io.Reader` and `*bytes.Buffer` in `x.‸` where `var x struct{ io.Reader; *bytes.Buffer }`.
+// The members of these types are promoted, so they should be offered alongside the struct's own fields.
+//
+// Embedded types that are structs declared in the file are followed, so their embedded types are included too.
+// The struct type is resolved as in SelectorBaseType.
+func (cx *CurCtx) PromotedMembersSources() []ast.Expr {
+	base, _ := cx.SelectorBase()
+	id, _ := base.(*ast.Ident)
+	if id == nil {
+		return nil
+	}
+	typ, ok := cx.varType(id.Name)
+	if !ok {
+		return nil
+	}
+
+	l := []ast.Expr{}
+	seen := map[string]bool{}
+	var add func(typ ast.Expr)
+	add = func(typ ast.Expr) {
+		if x, ok := typ.(*ast.StarExpr); ok {
+			typ = x.X
+		}
+		st, _ := cx.underlyingType(typ).(*ast.StructType)
+		if st == nil || st.Fields == nil {
+			return
+		}
+		for _, f := range st.Fields.List {
+			if len(f.Names) != 0 {
+				continue
+			}
+			s, _ := cx.Print(f.Type)
+			if seen[s] {
+				continue
+			}
+			seen[s] = true
+			l = append(l, f.Type)
+			add(f.Type)
+		}
+	}
+	add(typ)
+	return l
+}
+
+// typeNameIdent returns the identifier naming the unqualified type typ e.g. `T` in `T` or `T[int]`
+func typeNameIdent(typ ast.Expr) *ast.Ident {
+	switch x := typ.(type) {
+	case *ast.Ident:
+		return x
+	case *ast.IndexExpr:
+		return typeNameIdent(x.X)
+	case *ast.IndexListExpr:
+		return typeNameIdent(x.X)
+	case *ast.ParenExpr:
+		return typeNameIdent(x.X)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package cursor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPromotedMembersSources(t *testing.T) {
+	decls := "package p\ntype T struct {\n\tio.Reader\n\t*Inner\n\tName string\n}\ntype Inner struct {\n\tbytes.Buffer\n\t*T\n}\n"
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"func f() {\n\tvar x T\n\tx.‸\n}\n", "io.Reader, *Inner, bytes.Buffer, *T"},
+		{"func f() {\n\tx := &Inner{}\n\tx.R‸\n}\n", "bytes.Buffer, *T, io.Reader, *Inner"},
+		{"func f() {\n\tvar x struct{ sync.Mutex }\n\tx.‸\n}\n", "sync.Mutex"},
+		{"func f() {\n\tvar x struct{ N int }\n\tx.‸\n}\n", ""},
+		{"func f() {\n\tvar x io.Reader\n\tx.‸\n}\n", ""},
+		{"func f() {\n\tvar x T\n\t‸x.Name\n}\n", ""},
+	}
+	for _, c := range cases {
+		src := decls + c.src
+		cx := newTestCurCtx(t, src)
+		l := []string{}
+		for _, x := range cx.PromotedMembersSources() {
+			s, _ := cx.Print(x)
+			l = append(l, s)
+		}
+		if got := strings.Join(l, ", "); got != c.want {
+			t.Errorf("PromotedMembersSources() = `%s`, want `%s` in %q", got, c.want, c.src)
+		}
+	}
+}
+
+func TestSelectorBase(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"x.‸", "x"},
+		{"x.N‸", "x"},
+		{"foo.Bar().‸", "foo.Bar()"},
+		{"foo.Bar().‸ ", "foo.Bar()"},
+		{"foo.Bar().B‸", "foo.Bar()"},
+		{"foo.Bar(a, b).‸", "foo.Bar(a, b)"},
+		{"v := foo.Bar().‸", "foo.Bar()"},
+		{"foo.Bar().Baz().‸", "foo.Bar().Baz()"},
+		{"g(foo.Bar().‸)", "foo.Bar()"},
+		{"foo.Bar().‸\n\tx := 1", "foo.Bar()"},
+		{"fo‸o.Bar()", ""},
+		{"foo.Bar(‸)", ""},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		base, ok := cx.SelectorBase()
+		got := ""
+		if base != nil {
+			got, _ = cx.Print(base)
+		}
+		if got != c.want || ok != (c.want != "") {
+			t.Errorf("SelectorBase() = (`%s`, %v), want (`%s`, %v) in %q", got, ok, c.want, c.want != "", c.src)
+		}
+		if ok && !cx.Scope.Is(SelectorScope) {
+			t.Errorf("Scope = %v, want SelectorScope in %q", cx.Scope, c.src)
+		}
+	}
+}
+
+func TestMethodExprReceiverContext(t *testing.T) {
+	cases := []struct {
+		src  string
+		want bool
+	}{
+		{"f := T‸.M", true},
+		{"f := (*T‸).M", true},
+		{"f := (*Unknown‸).M", true},
+		{"f := Unknown‸.M", false},
+		{"f := G[int]‸.M", true},
+		{"f := v‸.M", false},
+		{"f := (*v‸).M", false},
+		{"f := T.M‸", false},
+		{"f := (*T).M‸", false},
+		{"f := (‸T).M", true},
+	}
+	for _, c := range cases {
+		src := "package p\ntype T struct{}\ntype G[P any] struct{}\nfunc f() {\n\tvar v *T\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		if got := cx.MethodExprReceiverContext(); got != c.want {
+			t.Errorf("MethodExprReceiverContext() = %v, want %v in %q", got, c.want, c.src)
+		}
+	}
+}
+
+func TestSelectorChainText(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"pkg.Type.Meth‸od.Other()", "pkg.Type.Method"},
+		{"pkg.Type.‸", "pkg.Type."},
+		{"x.‸", "x."},
+		{"foo.Bar().‸", "foo.Bar()."},
+		{"foo.Bar(a, b).Baz‸()", "foo.Bar(a, b).Baz"},
+		{"a[i].b.c‸", "a[i].b.c"},
+		{"p‸kg.Type", ""},
+		{"‸", ""},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		got, ok := cx.SelectorChainText()
+		if got != c.want || ok != (c.want != "") {
+			t.Errorf("SelectorChainText() = (%q, %v), want (%q, %v) in %q", got, ok, c.want, c.want != "", c.src)
+		}
+	}
+}
+
+func TestSelectorBaseType(t *testing.T) {
+	cases := []struct {
+		src       string
+		typeName  string
+		isPointer bool
+	}{
+		{"func (t T) f() {\n\tt.‸\n}", "T", false},
+		{"func (t *T) f() {\n\tt.‸\n}", "T", true},
+		{"func f(t *T, g G[int]) {\n\tg.‸\n}", "G", false},
+		{"func f(t *T) {\n\tt.N‸\n}", "T", true},
+		{"func f() {\n\tvar x T\n\tx.‸\n}", "T", false},
+		{"func f() {\n\tx := T{}\n\tx.‸\n}", "T", false},
+		{"func f() {\n\tx := &T{}\n\tx.‸\n}", "T", true},
+		{"func f() {\n\tx := new(T)\n\tx.‸\n}", "T", true},
+		{"func f() {\n\tx.‸\n}", "", false},
+		{"func f() {\n\tx := pkg.T{}\n\tx.‸\n}", "", false},
+		{"func f() {\n\tx := g()\n\tx.‸\n}", "", false},
+	}
+	for _, c := range cases {
+		src := "package p\ntype T struct{ N int }\ntype G[P any] struct{}\n" + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		typeName, isPointer, ok := cx.SelectorBaseType()
+		if typeName != c.typeName || isPointer != c.isPointer || ok != (c.typeName != "") {
+			t.Errorf("SelectorBaseType() = (%q, %v, %v), want (%q, %v, %v) in %q", typeName, isPointer, ok, c.typeName, c.isPointer, c.typeName != "", c.src)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package cursor
+
+import (
+	"encoding/json"
+)
+
+const (
+	// CurSnapshotVersion is the version of the CurSnapshot JSON format.
+	// It's incremented whenever fields are removed or their meaning changes.
+	CurSnapshotVersion = 1
+)
+
+// CurSnapshot is a serializable summary of a CurCtx's classification of the cursor
+type CurSnapshot struct {
+	Version              int      `json:"version"`
+	Filename             string   `json:"filename"`
+	Pos                  int      `json:"pos"`
+	Scopes               []string `json:"scopes"`
+	Prefix               string   `json:"prefix"`
+	PkgName              string   `json:"pkgName"`
+	IsTestFile           bool     `json:"isTestFile"`
+	ExpectsType          bool     `json:"expectsType"`
+	BuiltinsAllowed      bool     `json:"builtinsAllowed"`
+	LineHasPrecedingCode bool     `json:"lineHasPrecedingCode"`
+	AtEOF                bool     `json:"atEOF"`
+}
+
+// MarshalJSON implements json.Marshaler, making sure Version and Scopes are always set
+func (s CurSnapshot) MarshalJSON() ([]byte, error) {
+	type snapshot CurSnapshot
+	if s.Version == 0 {
+		s.Version = CurSnapshotVersion
+	}
+	if s.Scopes == nil {
+		s.Scopes = []string{}
+	}
+	return json.Marshal(snapshot(s))
+}
+
+// Snapshot returns a CurSnapshot of cx
+func (cx *CurCtx) Snapshot() CurSnapshot {
+	prefix := string(cx.Src[identStart(cx.Src, cx.srcPos):cx.srcPos])
+	if s, ok := cx.ImportPathPrefix(); ok {
+		prefix = s
+	} else if s, ok := cx.EmbedPattern(); ok {
+		prefix = s
+	}
+	s := CurSnapshot{
+		Version:              CurSnapshotVersion,
+		Pos:                  cx.Pos,
+		Scopes:               cx.Scope.Names(),
+		Prefix:               prefix,
+		PkgName:              cx.PkgName,
+		IsTestFile:           cx.IsTestFile,
+		ExpectsType:          cx.ExpectsType(),
+		BuiltinsAllowed:      cx.BuiltinsAllowed(),
+		LineHasPrecedingCode: cx.LineHasPrecedingCode(),
+		AtEOF:                cx.AtEOF(),
+	}
+	if cx.View != nil {
+		s.Filename = cx.View.Filename()
+	}
+	return s
+}
@@ -0,0 +1,38 @@
+package cursor
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCurSnapshotMarshalJSON(t *testing.T) {
+	cx := newTestCurCtx(t, "package p\nfunc f() {\n\tfo‸\n}\n")
+	b, err := json.Marshal(cx.Snapshot())
+	if err != nil {
+		t.Fatalf("json.Marshal(Snapshot()) failed: %s", err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatalf("json.Unmarshal failed: %s", err)
+	}
+	if v := m["version"]; v != float64(CurSnapshotVersion) {
+		t.Errorf("version = %v, want %v", v, CurSnapshotVersion)
+	}
+	if v := m["prefix"]; v != "fo" {
+		t.Errorf("prefix = %v, want `fo`", v)
+	}
+	if v, _ := m["scopes"].([]interface{}); len(v) == 0 {
+		t.Errorf("scopes is empty in %s", b)
+	}
+
+	b, err = json.Marshal(CurSnapshot{})
+	if err != nil {
+		t.Fatalf("json.Marshal(CurSnapshot{}) failed: %s", err)
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatalf("json.Unmarshal failed: %s", err)
+	}
+	if v := m["version"]; v != float64(CurSnapshotVersion) {
+		t.Errorf("zero value version = %v, want %v", v, CurSnapshotVersion)
+	}
+}
@@ -1,9 +1,25 @@
 package cursor
 
 import (
+	"margo.sh/mg"
+	"strings"
 	"testing"
 )
 
+// cursorMarker marks the cursor position in test sources
+const cursorMarker = "‸"
+
+func newTestCurCtx(t *testing.T, src string) *CurCtx {
+	t.Helper()
+
+	pos := strings.Index(src, cursorMarker)
+	if pos < 0 {
+		t.Fatalf("source has no cursor marker: %q", src)
+	}
+	src = src[:pos] + src[pos+len(cursorMarker):]
+	return NewCurCtx(mg.NewTestingCtx(nil), []byte(src), pos)
+}
+
 func TestCurScopeStringer(t *testing.T) {
 	if cs := CurScope(0); cs.String() == "" {
 		t.Errorf("%#v doesn't have a String() value", cs)
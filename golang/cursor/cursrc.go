@@ -0,0 +1,98 @@
+package cursor
+
+import (
+	"bytes"
+	"go/ast"
+	"go/scanner"
+	"go/token"
+	"margo.sh/golang/goutil"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LineHasPrecedingCode returns true if there is code (anything other than whitespace and comments)
+// before the cursor on the cursor's line.
+func (cx *CurCtx) LineHasPrecedingCode() bool {
+	return srcHasCode(cx.Src[lineStart(cx.Src, cx.srcPos):cx.srcPos])
+}
+
+// AtEOF returns true if there is nothing but whitespace after the cursor
+func (cx *CurCtx) AtEOF() bool {
+	return len(bytes.TrimSpace(cx.Src[cx.srcPos:])) == 0
+}
+
+// BraceBalance returns the number of opening and closing braces in the source.
+// Braces inside strings, runes and comments are not counted.
+//
+// If open != close, the AST is likely degraded and scopes might be unreliable.
+func (cx *CurCtx) BraceBalance() (open int, close int) {
+	var sc scanner.Scanner
+	sc.Init(token.NewFileSet().AddFile("", -1, len(cx.Src)), cx.Src, nil, 0)
+	for {
+		_, tok, _ := sc.Scan()
+		switch tok {
+		case token.EOF:
+			return open, close
+		case token.LBRACE:
+			open++
+		case token.RBRACE:
+			close++
+		}
+	}
+}
+
+// isStmtStart returns true if the cursor is at the start of a statement inside a block,
+// optionally after the identifier currently being typed.
+func (cx *CurCtx) isStmtStart() bool {
+	if cx.BlockStmt == nil || cx.Scope.Is(CommentScope, StringScope) {
+		return false
+	}
+	start := identStart(cx.Src, cx.srcPos)
+	if srcHasCode(cx.Src[lineStart(cx.Src, start):start]) {
+		return false
+	}
+	for i := len(cx.Nodes) - 1; i >= 0; i-- {
+		switch x := cx.Nodes[i].(type) {
+		case *ast.BlockStmt, *ast.CaseClause, *ast.CommClause:
+			return true
+		case ast.Stmt:
+			return cx.TokenFile.Offset(x.Pos()) >= start
+		}
+	}
+	return false
+}
+
+// lineStart returns the offset of the start of the line containing pos
+func lineStart(src []byte, pos int) int {
+	return bytes.LastIndexByte(src[:pos], '\n') + 1
+}
+
+// identStart returns the offset of the start of the identifier that ends at pos
+func identStart(src []byte, pos int) int {
+	for pos > 0 {
+		r, n := utf8.DecodeLastRune(src[:pos])
+		if !goutil.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		pos -= n
+	}
+	return pos
+}
+
+// srcHasCode returns true if s contains any tokens other than comments
+func srcHasCode(s []byte) bool {
+	var sc scanner.Scanner
+	sc.Init(token.NewFileSet().AddFile("", -1, len(s)), s, nil, scanner.ScanComments)
+	for {
+		_, tok, lit := sc.Scan()
+		switch {
+		case tok == token.EOF:
+			return false
+		case tok == token.COMMENT:
+		case tok == token.SEMICOLON && lit == "\n":
+			// automatically inserted
+		default:
+			return true
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package cursor
+
+import (
+	"testing"
+)
+
+func TestLineHasPrecedingCode(t *testing.T) {
+	cases := []struct {
+		src       string
+		code      bool
+		stmtStart bool
+	}{
+		{"package p\nfunc f() {\n\t‸\n}\n", false, true},
+		{"package p\nfunc f() {\n\tf‸\n}\n", true, true},
+		{"package p\nfunc f() {\n\t/* x */ fo‸o\n}\n", true, true},
+		{"package p\nfunc f() {\n\tx := fo‸o\n}\n", true, false},
+		{"package p\nfunc f() {\n\tx := foo(\n\t\tba‸r)\n}\n", true, false},
+		{"package p\nfunc f() {\n\t// foo ‸\n}\n", false, false},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		if got := cx.LineHasPrecedingCode(); got != c.code {
+			t.Errorf("LineHasPrecedingCode() = %v, want %v in %q", got, c.code, c.src)
+		}
+		if got := cx.Scope.Is(StmtStartScope); got != c.stmtStart {
+			t.Errorf("Scope.Is(StmtStartScope) = %v, want %v in %q", got, c.stmtStart, c.src)
+		}
+	}
+}
+
+func TestAtEOF(t *testing.T) {
+	cases := []struct {
+		src   string
+		scope CurScope
+		eof   bool
+	}{
+		{"package p\nfunc f() {\n\tfoo‸", IdentScope, true},
+		{"package p\nfunc f() {\n\tfoo‸\n", IdentScope, true},
+		{"package p\nfunc f() {\n\tfoo‸\n}\n", IdentScope, false},
+		{"package p\n\n// comment‸", CommentScope, true},
+		{"package p\n\n// comment‸\n", CommentScope, true},
+		{"package p\n\nvar x = 1\n‸", FileScope, true},
+		{"package p\n\nvar x = 1\n‸\n", FileScope, true},
+		{"package p\n\nvar x = 1‸", VarScope, true},
+		{"package p\n\nvar x = 1‸\n", VarScope, true},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		if got := cx.AtEOF(); got != c.eof {
+			t.Errorf("AtEOF() = %v, want %v in %q", got, c.eof, c.src)
+		}
+		if !cx.Scope.Is(c.scope) {
+			t.Errorf("Scope = %v, want %v in %q", cx.Scope, c.scope, c.src)
+		}
+	}
+}
+
+func TestBraceBalance(t *testing.T) {
+	cases := []struct {
+		src   string
+		open  int
+		close int
+	}{
+		{"package p\nfunc f() {\n\t‸\n}\n", 1, 1},
+		{"package p\nfunc f() {\n\tif x {\n\t\t‸\n}\n", 2, 1},
+		{"package p\nfunc f() {\n\t‸\n}\n}\n", 1, 2},
+		{"package p\nvar x = T{A: []int{1}}‸\n", 2, 2},
+		{"package p\nfunc f() {\n\ts := \"{{\" + `}`\n\tr := '{'‸\n}\n", 1, 1},
+		{"package p\nfunc f() {\n\t// {\n\t/* { } } */‸\n}\n", 1, 1},
+		{"package p\n‸", 0, 0},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		open, close := cx.BraceBalance()
+		if open != c.open || close != c.close {
+			t.Errorf("BraceBalance() = (%d, %d), want (%d, %d) in %q", open, close, c.open, c.close, c.src)
+		}
+	}
+}
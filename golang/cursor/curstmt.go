@@ -0,0 +1,571 @@
+package cursor
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"margo.sh/golang/goutil"
+	"regexp"
+)
+
+var (
+	deferredSelectorPat = regexp.MustCompile(`^\s*(defer|go)\s+(.+)\.\w*$`)
+	deferGoStmtPat      = regexp.MustCompile(`^\s*(defer|go)\s+(.*\S)\s*$`)
+)
+
+// GoDeferKind describes the kind of statement, defer or go
+type GoDeferKind uint8
+
+const (
+	// UnknownGoDeferKind is the zero value, the cursor is not in a defer or go statement
+	UnknownGoDeferKind GoDeferKind = iota
+
+	// DeferStmtKind is a defer statement e.g. `defer f()`
+	DeferStmtKind
+
+	// GoStmtKind is a go statement e.g. `go f()`
+	GoStmtKind
+)
+
+// InDeferredOrGoClosure returns the kind of the defer or go statement whose func literal's body
+// immediately encloses the cursor e.g. `defer func() { ‸ }()` or `go func() { ‸ }`.
+//
+// The closure doesn't need to be called yet, or even closed, but the cursor must be in its body, not its signature.
+// If ok is true, the cursor is in FuncLitScope, BlockScope and DeferScope or GoScope.
+func (cx *CurCtx) InDeferredOrGoClosure() (kind GoDeferKind, ok bool) {
+	for i := len(cx.Nodes) - 1; i >= 0; i-- {
+		fl, _ := cx.Nodes[i].(*ast.FuncLit)
+		if fl == nil {
+			continue
+		}
+		if i < 2 || fl.Body == nil || cx.TokenPos <= fl.Body.Lbrace || cx.TokenPos > fl.Body.Rbrace {
+			return UnknownGoDeferKind, false
+		}
+		call, _ := cx.Nodes[i-1].(*ast.CallExpr)
+		if call == nil || call.Fun != fl {
+			return UnknownGoDeferKind, false
+		}
+		switch x := cx.Nodes[i-2].(type) {
+		case *ast.DeferStmt:
+			if x.Call == call {
+				return DeferStmtKind, true
+			}
+		case *ast.GoStmt:
+			if x.Call == call {
+				return GoStmtKind, true
+			}
+		}
+		return UnknownGoDeferKind, false
+	}
+	return cx.scanDeferredOrGoClosure()
+}
+
+// scanDeferredOrGoClosure is a token-based fallback for InDeferredOrGoClosure
+// for when the closure is not called, so the parser discards the statement
+func (cx *CurCtx) scanDeferredOrGoClosure() (kind GoDeferKind, ok bool) {
+	var bs *ast.BlockStmt
+	if !cx.Set(&bs) {
+		return UnknownGoDeferKind, false
+	}
+	var bad *ast.BadStmt
+	for _, s := range bs.List {
+		if x, ok := s.(*ast.BadStmt); ok && x.From < cx.TokenPos {
+			bad = x
+		}
+	}
+	if bad == nil {
+		return UnknownGoDeferKind, false
+	}
+
+	src := cx.Src[cx.TokenFile.Offset(bad.From):cx.srcPos]
+	var sc scanner.Scanner
+	sc.Init(token.NewFileSet().AddFile("", -1, len(src)), src, nil, 0)
+	switch _, tok, _ := sc.Scan(); tok {
+	case token.DEFER:
+		kind = DeferStmtKind
+	case token.GO:
+		kind = GoStmtKind
+	default:
+		return UnknownGoDeferKind, false
+	}
+	if _, tok, _ := sc.Scan(); tok != token.FUNC {
+		return UnknownGoDeferKind, false
+	}
+	parens, braces := 0, 0
+	inBody := false
+	for {
+		_, tok, _ := sc.Scan()
+		switch tok {
+		case token.EOF:
+			if !inBody {
+				return UnknownGoDeferKind, false
+			}
+			return kind, true
+		case token.LPAREN:
+			parens++
+		case token.RPAREN:
+			parens--
+		case token.LBRACE:
+			braces++
+			if parens == 0 {
+				inBody = true
+			}
+		case token.RBRACE:
+			braces--
+			if inBody && braces == 0 {
+				// the closure has ended
+				return UnknownGoDeferKind, false
+			}
+		}
+	}
+}
+
+// CommaOkKind describes the kind of expression in a comma-ok assignment
+type CommaOkKind uint8
+
+const (
+	// UnknownCommaOk is the zero value, the cursor is not after a comma-ok assignment
+	UnknownCommaOk CommaOkKind = iota
+
+	// MapIndexCommaOk is a map index e.g. `v, ok := m[k]`
+	MapIndexCommaOk
+
+	// TypeAssertCommaOk is a type assertion e.g. `v, ok := x.(T)`
+	TypeAssertCommaOk
+
+	// ChanRecvCommaOk is a channel receive e.g. `v, ok := <-ch`
+	ChanRecvCommaOk
+)
+
+// CommaOkContext returns the names assigned by the comma-ok init statement of the if or switch statement
+// enclosing the cursor e.g. `v` and `ok` in `if v, ok := m[k]; ‸ {}`, so `ok`-based conditions can be offered.
+// Blank identifiers are returned as `_`.
+//
+// The cursor must be after the init statement e.g. in the condition or the body.
+// A map index can't be distinguished from a slice index or generic instantiation syntactically,
+// so any index expression is reported as MapIndexCommaOk.
+func (cx *CurCtx) CommaOkContext() (valueName, okName string, kind CommaOkKind, ok bool) {
+	for i := len(cx.Nodes) - 1; i >= 0; i-- {
+		var init ast.Stmt
+		switch x := cx.Nodes[i].(type) {
+		case *ast.IfStmt:
+			init = x.Init
+		case *ast.SwitchStmt:
+			init = x.Init
+		case *ast.FuncLit:
+			return "", "", UnknownCommaOk, false
+		default:
+			continue
+		}
+		asn, _ := init.(*ast.AssignStmt)
+		if asn == nil || cx.TokenPos <= asn.End() || len(asn.Lhs) != 2 || len(asn.Rhs) != 1 {
+			continue
+		}
+		v, _ := asn.Lhs[0].(*ast.Ident)
+		k, _ := asn.Lhs[1].(*ast.Ident)
+		if v == nil || k == nil {
+			continue
+		}
+		switch x := asn.Rhs[0].(type) {
+		case *ast.IndexExpr:
+			kind = MapIndexCommaOk
+		case *ast.TypeAssertExpr:
+			if x.Type != nil {
+				kind = TypeAssertCommaOk
+			}
+		case *ast.UnaryExpr:
+			if x.Op == token.ARROW {
+				kind = ChanRecvCommaOk
+			}
+		}
+		if kind != UnknownCommaOk {
+			return v.Name, k.Name, kind, true
+		}
+	}
+	return "", "", UnknownCommaOk, false
+}
+
+// IncompleteStmtKeyword returns the keyword of the return, defer or go statement on the cursor's line
+// iff the statement is still being typed e.g. `return ‸`, `defer ‸` or `go f‸`.
+//
+// A statement is incomplete if nothing follows the keyword, or the parser didn't produce it,
+// e.g. because `defer` and `go` statements without a call are discarded.
+// The corresponding ReturnScope, DeferScope or GoScope is set in such cases.
+func (cx *CurCtx) IncompleteStmtKeyword() (token.Token, bool) {
+	if cx.enclosingFunc() == nil || cx.Scope.Is(StringScope, CommentScope) {
+		return token.ILLEGAL, false
+	}
+	src := cx.Src[lineStart(cx.Src, cx.srcPos):cx.srcPos]
+	var sc scanner.Scanner
+	sc.Init(token.NewFileSet().AddFile("", -1, len(src)), src, nil, 0)
+	_, kw, _ := sc.Scan()
+	switch kw {
+	case token.RETURN, token.DEFER, token.GO:
+	default:
+		return token.ILLEGAL, false
+	}
+	if _, tok, lit := sc.Scan(); tok == token.EOF || (tok == token.SEMICOLON && lit == "\n") {
+		return kw, true
+	}
+	for _, n := range cx.Nodes {
+		var tok token.Token
+		switch n.(type) {
+		case *ast.ReturnStmt:
+			tok = token.RETURN
+		case *ast.DeferStmt:
+			tok = token.DEFER
+		case *ast.GoStmt:
+			tok = token.GO
+		}
+		if tok == kw {
+			return token.ILLEGAL, false
+		}
+	}
+	return kw, true
+}
+
+// DeferGoNeedsCall returns the kind of the defer or go statement on the cursor's line
+// iff its expression is not a call e.g. `go f‸` or `defer mu.Unlock‸`, which the compiler rejects.
+//
+// The parser discards such statements, so the line is parsed on its own and,
+// like the parser, expressions that are still being typed e.g. `defer x.‸` are ignored.
+func (cx *CurCtx) DeferGoNeedsCall() (kind GoDeferKind, ok bool) {
+	if cx.enclosingFunc() == nil || cx.Scope.Is(StringScope, CommentScope) {
+		return UnknownGoDeferKind, false
+	}
+	switch cx.enclosingStmt().(type) {
+	case *ast.DeferStmt, *ast.GoStmt:
+		return UnknownGoDeferKind, false
+	}
+	start := lineStart(cx.Src, cx.srcPos)
+	line := cx.Src[start:]
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	m := deferGoStmtPat.FindSubmatch(line)
+	if m == nil {
+		return UnknownGoDeferKind, false
+	}
+	x, err := parser.ParseExpr(string(m[2]))
+	if err != nil {
+		return UnknownGoDeferKind, false
+	}
+	if p, ok := x.(*ast.ParenExpr); ok {
+		x = p.X
+	}
+	if _, isCall := x.(*ast.CallExpr); isCall {
+		return UnknownGoDeferKind, false
+	}
+	if string(m[1]) == "go" {
+		return GoStmtKind, true
+	}
+	return DeferStmtKind, true
+}
+
+// DeferredMethodContext returns the base expression of the method value being called
+// in a defer or go statement e.g. `obj` in `defer obj.Cl‸` or `go s.srv.R‸()`.
+//
+// If ok is true, the cursor is in SelectorScope and DeferScope or GoScope.
+// Reducers should prefer methods (not fields) after the dot.
+func (cx *CurCtx) DeferredMethodContext() (base ast.Expr, ok bool) {
+	base, _, ok = cx.deferredMethodCtx()
+	return base, ok
+}
+
+// deferredMethodCtx implements DeferredMethodContext, additionally returning the token DEFER or GO
+func (cx *CurCtx) deferredMethodCtx() (base ast.Expr, tok token.Token, ok bool) {
+	var call *ast.CallExpr
+	switch x := cx.enclosingStmt().(type) {
+	case *ast.DeferStmt:
+		call, tok = x.Call, token.DEFER
+	case *ast.GoStmt:
+		call, tok = x.Call, token.GO
+	}
+	if call != nil {
+		sel, _ := call.Fun.(*ast.SelectorExpr)
+		if sel == nil || cx.TokenPos <= sel.X.End() || cx.TokenPos > sel.Sel.End() {
+			return nil, 0, false
+		}
+		return sel.X, tok, true
+	}
+
+	// `defer x.` without the call parens isn't parsed as a defer statement
+	if cx.enclosingFunc() == nil || cx.Scope.Is(StringScope, CommentScope) {
+		return nil, 0, false
+	}
+	m := deferredSelectorPat.FindSubmatch(cx.Src[lineStart(cx.Src, cx.srcPos):cx.srcPos])
+	if m == nil {
+		return nil, 0, false
+	}
+	base, err := parser.ParseExpr(string(m[2]))
+	if err != nil {
+		return nil, 0, false
+	}
+	if string(m[1]) == "go" {
+		return base, token.GO, true
+	}
+	return base, token.DEFER, true
+}
+
+// AssignIsDefine returns true if the assignment statement enclosing the cursor is a short variable declaration
+// e.g. `x‸ := 1`, where new names should be offered on the LHS, rather than e.g. `x‸ = 1` or `x‸ += 1`.
+// ok is false if the cursor is not in an assignment statement.
+func (cx *CurCtx) AssignIsDefine() (isDefine bool, ok bool) {
+	asn, _ := cx.enclosingStmt().(*ast.AssignStmt)
+	if asn == nil {
+		return false, false
+	}
+	return asn.Tok == token.DEFINE, true
+}
+
+// EnclosingStmtRange returns the range of the innermost statement enclosing the cursor.
+// Use cx.TokenFile.Offset to convert it to byte offsets in cx.Src.
+//
+// If includeLeadingBlankLines is true and the statement is alone on its line(s),
+// the range is extended to consume the preceding blank lines, the indentation and the trailing newline,
+// so that deleting it doesn't leave stray blank lines behind.
+func (cx *CurCtx) EnclosingStmtRange(includeLeadingBlankLines bool) (goutil.PosEnd, bool) {
+	stmt := cx.enclosingStmt()
+	if stmt == nil {
+		return goutil.PosEnd{}, false
+	}
+	pe := goutil.PosEnd{P: stmt.Pos(), E: stmt.End()}
+	if !includeLeadingBlankLines {
+		return pe, true
+	}
+	src := cx.Src
+	start, end := cx.TokenFile.Offset(pe.P), cx.TokenFile.Offset(pe.E)
+	i := start
+	for i > 0 && (src[i-1] == ' ' || src[i-1] == '\t') {
+		i--
+	}
+	j := end
+	for j < len(src) && (src[j] == ' ' || src[j] == '\t' || src[j] == '\r') {
+		j++
+	}
+	if (i != 0 && src[i-1] != '\n') || (j != len(src) && src[j] != '\n') {
+		// the statement shares its line with other code
+		return pe, true
+	}
+	for i > 0 && (src[i-1] == ' ' || src[i-1] == '\t' || src[i-1] == '\r' || src[i-1] == '\n') {
+		i--
+	}
+	if i != 0 {
+		// keep the newline ending the previous line
+		i += bytes.IndexByte(src[i:], '\n') + 1
+	}
+	if j != len(src) {
+		j++
+	}
+	return goutil.PosEnd{P: cx.TokenFile.Pos(i), E: cx.TokenFile.Pos(j)}, true
+}
+
+// enclosingStmt returns the innermost statement enclosing the cursor, or nil
+func (cx *CurCtx) enclosingStmt() ast.Stmt {
+	for i := len(cx.Nodes) - 1; i >= 0; i-- {
+		switch x := cx.Nodes[i].(type) {
+		case *ast.BlockStmt:
+			return nil
+		case ast.Stmt:
+			return x
+		}
+	}
+	return nil
+}
+
+// NearestLoopLabel returns the label of the innermost labeled for, range, switch, type switch or select statement
+// whose body encloses the cursor e.g. `Outer` in `Outer: for { for { ‸ } }`, for offering `break Outer` or `continue Outer`.
+// label is empty if none of the enclosing statements are labeled; ok is false if the cursor isn't in such a statement.
+func (cx *CurCtx) NearestLoopLabel() (label string, ok bool) {
+	for i := len(cx.Nodes) - 1; i >= 0; i-- {
+		var body *ast.BlockStmt
+		switch x := cx.Nodes[i].(type) {
+		case *ast.ForStmt:
+			body = x.Body
+		case *ast.RangeStmt:
+			body = x.Body
+		case *ast.SwitchStmt:
+			body = x.Body
+		case *ast.TypeSwitchStmt:
+			body = x.Body
+		case *ast.SelectStmt:
+			body = x.Body
+		case *ast.FuncLit, *ast.FuncDecl:
+			return "", ok
+		default:
+			continue
+		}
+		if body == nil || !goutil.NodeEnclosesPos(body, cx.TokenPos) {
+			continue
+		}
+		ok = true
+		if i > 0 {
+			if ls, isLabel := cx.Nodes[i-1].(*ast.LabeledStmt); isLabel {
+				return ls.Label.Name, true
+			}
+		}
+	}
+	return "", ok
+}
+
+// GotoWouldSkipDecls returns true if `goto label` at the cursor would jump over
+// variable declarations into their scope, which the compiler forbids.
+//
+// It's a lexical approximation: only forward jumps can skip declarations,
+// and only variables declared directly in the block containing the label,
+// between the cursor and the label, are considered.
+func (cx *CurCtx) GotoWouldSkipDecls(label string) bool {
+	list, i := cx.labeledStmt(label)
+	if list == nil || list[i].Pos() <= cx.TokenPos {
+		return false
+	}
+	for _, s := range list[:i] {
+		if s.Pos() <= cx.TokenPos {
+			continue
+		}
+		for _, d := range stmtDecls(s) {
+			if d.Tok == token.VAR {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// labeledStmt returns the statement list containing the statement labeled label
+// in the function enclosing the cursor, and its index in the list
+func (cx *CurCtx) labeledStmt(label string) ([]ast.Stmt, int) {
+	_, body := funcTypeBody(cx.enclosingFunc())
+	if body == nil {
+		return nil, -1
+	}
+	var list []ast.Stmt
+	idx := -1
+	find := func(l []ast.Stmt) {
+		for i, s := range l {
+			if ls, ok := s.(*ast.LabeledStmt); ok && ls.Label.Name == label {
+				list, idx = l, i
+			}
+		}
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		if list != nil {
+			return false
+		}
+		switch x := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.BlockStmt:
+			find(x.List)
+		case *ast.CaseClause:
+			find(x.Body)
+		case *ast.CommClause:
+			find(x.Body)
+		}
+		return true
+	})
+	return list, idx
+}
+
+// InExprStmt returns the innermost expression statement enclosing the cursor e.g. `obj.Method()` or `x + 1`,
+// so a reducer can offer to assign its value e.g. `x := obj.Method()`.
+//
+// Channel receives e.g. `<-done` are not included, because discarding the received value is idiomatic.
+// Use ResultDiscarded to check whether a call statement's value is discarded.
+func (cx *CurCtx) InExprStmt() (*ast.ExprStmt, bool) {
+	var es *ast.ExprStmt
+	if !cx.Set(&es) {
+		return nil, false
+	}
+	x := es.X
+	for {
+		px, ok := x.(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		x = px.X
+	}
+	if ue, ok := x.(*ast.UnaryExpr); ok && ue.Op == token.ARROW {
+		return nil, false
+	}
+	return es, true
+}
+
+var (
+	// resultBuiltins is the set of builtin functions whose result is pointless to discard.
+	// copy and recover are excluded because their results are routinely ignored.
+	resultBuiltins = map[string]bool{
+		"append":  true,
+		"cap":     true,
+		"complex": true,
+		"imag":    true,
+		"len":     true,
+		"make":    true,
+		"max":     true,
+		"min":     true,
+		"new":     true,
+		"real":    true,
+	}
+)
+
+// ResultDiscarded returns the call of the expression statement enclosing the cursor
+// iff the called function returns results that are discarded e.g. `obj.Method(‸)` as a statement,
+// so a reducer can offer to capture them e.g. `x := obj.Method()` or `_ = obj.Method()`.
+//
+// Only functions whose signature is known from the file, and builtins like append, are considered.
+// ok is false for calls that return nothing, and calls whose signature is unknown.
+func (cx *CurCtx) ResultDiscarded() (call *ast.CallExpr, ok bool) {
+	es, ok := cx.InExprStmt()
+	if !ok {
+		return nil, false
+	}
+	call, _ = es.X.(*ast.CallExpr)
+	if call == nil {
+		return nil, false
+	}
+	if ft := cx.calleeFuncType(call.Fun); ft != nil {
+		if ft.Results == nil || len(ft.Results.List) == 0 {
+			return nil, false
+		}
+		return call, true
+	}
+	if id, _ := call.Fun.(*ast.Ident); id != nil && resultBuiltins[id.Name] && !cx.isDeclared(id.Name) {
+		return call, true
+	}
+	return nil, false
+}
+
+// LoopBoundContext returns the condition of the for statement whose condition encloses the cursor
+// e.g. `i <= len(s)` in `for i := 0; i <= len(s)‸; i++ {}`.
+//
+// suspicious is true if the condition is an inclusive comparison against the length or capacity
+// e.g. `i <= len(s)` or `cap(s) >= i`, which is likely an off-by-one error when indexing.
+func (cx *CurCtx) LoopBoundContext() (cond ast.Expr, suspicious bool, ok bool) {
+	var fs *ast.ForStmt
+	if !cx.Set(&fs) || fs.Cond == nil || !goutil.NodeEnclosesPos(fs.Cond, cx.TokenPos) {
+		return nil, false, false
+	}
+	be, _ := fs.Cond.(*ast.BinaryExpr)
+	if be == nil {
+		return fs.Cond, false, true
+	}
+	switch be.Op {
+	case token.LEQ:
+		suspicious = cx.isLenCall(be.Y)
+	case token.GEQ:
+		suspicious = cx.isLenCall(be.X)
+	}
+	return fs.Cond, suspicious, true
+}
+
+// isLenCall returns true if x is a call to the builtin len or cap
+func (cx *CurCtx) isLenCall(x ast.Expr) bool {
+	call, _ := x.(*ast.CallExpr)
+	if call == nil {
+		return false
+	}
+	id, _ := call.Fun.(*ast.Ident)
+	return id != nil && (id.Name == "len" || id.Name == "cap") && !cx.isDeclared(id.Name)
+}
@@ -0,0 +1,398 @@
+package cursor
+
+import (
+	"go/token"
+	"testing"
+)
+
+func TestDeferredMethodContext(t *testing.T) {
+	cases := []struct {
+		src   string
+		base  string
+		scope CurScope
+	}{
+		{"package p\nfunc f() {\n\tdefer obj.‸\n}\n", "obj", DeferScope},
+		{"package p\nfunc f() {\n\tdefer obj.Cl‸\n}\n", "obj", DeferScope},
+		{"package p\nfunc f() {\n\tdefer obj.Cl‸ose()\n}\n", "obj", DeferScope},
+		{"package p\nfunc f() {\n\tgo s.srv.‸\n}\n", "s.srv", GoScope},
+		{"package p\nfunc f() {\n\tgo s.srv.R‸un()\n}\n", "s.srv", GoScope},
+		{"package p\nfunc f() {\n\tgo o‸bj.Run()\n}\n", "", 0},
+		{"package p\nfunc f() {\n\tdefer obj.Close(x.‸)\n}\n", "", 0},
+		{"package p\nfunc f() {\n\tdefer func() {\n\t\tobj.‸\n\t}()\n}\n", "", 0},
+		{"package p\nfunc f() {\n\tobj.‸\n}\n", "", 0},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		base, ok := cx.DeferredMethodContext()
+		got := ""
+		if base != nil {
+			got, _ = cx.Print(base)
+		}
+		if got != c.base || ok != (c.base != "") {
+			t.Errorf("DeferredMethodContext() = (`%s`, %v), want (`%s`, %v) in %q", got, ok, c.base, c.base != "", c.src)
+		}
+		if ok && !cx.Scope.Is(SelectorScope) || ok && !cx.Scope.Is(c.scope) {
+			t.Errorf("Scope = %v, want SelectorScope|%v in %q", cx.Scope, c.scope, c.src)
+		}
+	}
+
+	for _, src := range []string{
+		"package p\nfunc f() {\n\ts := `\ndefer obj.‸\n`\n}\n",
+		"package p\nfunc f() {\n\t/*\n\tdefer obj.‸\n\t*/\n}\n",
+		"package p\nvar s = `\ndefer obj.‸\n`\n",
+		"package p\n/*\ndefer obj.‸\n*/\n",
+		"package p\ndefer obj.‸\n",
+	} {
+		cx := newTestCurCtx(t, src)
+		if base, ok := cx.DeferredMethodContext(); ok {
+			t.Errorf("DeferredMethodContext() = (%v, true), want (nil, false) in %q", base, src)
+		}
+		if cx.Scope.Is(SelectorScope) {
+			t.Errorf("Scope = %v, want it to exclude SelectorScope in %q", cx.Scope, src)
+		}
+	}
+}
+
+func TestDeferGoNeedsCall(t *testing.T) {
+	cases := []struct {
+		src  string
+		kind GoDeferKind
+	}{
+		{"go f‸", GoStmtKind},
+		{"defer mu.Unlock‸", DeferStmtKind},
+		{"defer ‸mu.Unlock", DeferStmtKind},
+		{"go func() {}‸", GoStmtKind},
+		{"go f()‸", UnknownGoDeferKind},
+		{"defer mu.Unlock(‸)", UnknownGoDeferKind},
+		{"defer x.‸", UnknownGoDeferKind},
+		{"f‸", UnknownGoDeferKind},
+		{"gopher‸", UnknownGoDeferKind},
+		{"s := `\ndefer f‸\n`", UnknownGoDeferKind},
+		{"/*\ndefer f‸\n*/", UnknownGoDeferKind},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		kind, ok := cx.DeferGoNeedsCall()
+		if kind != c.kind || ok != (c.kind != UnknownGoDeferKind) {
+			t.Errorf("DeferGoNeedsCall() = (%v, %v), want (%v, %v) in %q", kind, ok, c.kind, c.kind != UnknownGoDeferKind, c.src)
+		}
+	}
+
+	for _, src := range []string{
+		"package p\nvar s = `\ndefer f‸\n`\n",
+		"package p\n/*\ndefer f‸\n*/\n",
+	} {
+		cx := newTestCurCtx(t, src)
+		if kind, ok := cx.DeferGoNeedsCall(); ok {
+			t.Errorf("DeferGoNeedsCall() = (%v, %v), want (%v, false) in %q", kind, ok, UnknownGoDeferKind, src)
+		}
+	}
+}
+
+func TestAssignIsDefine(t *testing.T) {
+	cases := []struct {
+		src      string
+		isDefine bool
+		ok       bool
+	}{
+		{"x‸ := 1", true, true},
+		{"x, y‸ := 1, 2", true, true},
+		{"x := ‸1", true, true},
+		{"x‸ = 1", false, true},
+		{"x‸ += 1", false, true},
+		{"x := func() {\n\t\t‸\n\t}", false, false},
+		{"x := func() {\n\t\ty = ‸1\n\t}", false, true},
+		{"f(‸)", false, false},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		isDefine, ok := cx.AssignIsDefine()
+		if isDefine != c.isDefine || ok != c.ok {
+			t.Errorf("AssignIsDefine() = (%v, %v), want (%v, %v) in %q", isDefine, ok, c.isDefine, c.ok, c.src)
+		}
+	}
+}
+
+func TestInDeferredOrGoClosure(t *testing.T) {
+	cases := []struct {
+		src  string
+		kind GoDeferKind
+	}{
+		{"defer func() {\n\t\t‸\n\t}()", DeferStmtKind},
+		{"defer func() {‸}()", DeferStmtKind},
+		{"go func() {\n\t\t‸\n\t}()", GoStmtKind},
+		{"defer func() {\n\t\t‸\n\t}", DeferStmtKind},
+		{"go func() {‸}", GoStmtKind},
+		{"defer func() {\n\t\tif x {\n\t\t\t‸\n\t\t}\n\t}", DeferStmtKind},
+		{"go func(a int) {\n\t\tx := 1\n\t\t‸\n\t}\n\tx := 2", GoStmtKind},
+		{"defer func(‸) {}()", UnknownGoDeferKind},
+		{"go func(‸) {}", UnknownGoDeferKind},
+		{"defer func() {}\n\t‸", UnknownGoDeferKind},
+		{"defer func() {\n\t\tg := func() {\n\t\t\t‸\n\t\t}\n\t}()", UnknownGoDeferKind},
+		{"f := func() {\n\t\t‸\n\t}", UnknownGoDeferKind},
+		{"‸", UnknownGoDeferKind},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		kind, ok := cx.InDeferredOrGoClosure()
+		if kind != c.kind || ok != (c.kind != UnknownGoDeferKind) {
+			t.Errorf("InDeferredOrGoClosure() = (%v, %v), want (%v, %v) in %q", kind, ok, c.kind, c.kind != UnknownGoDeferKind, c.src)
+		}
+		scope := DeferScope
+		if kind == GoStmtKind {
+			scope = GoScope
+		}
+		if ok && !(cx.Scope.Is(FuncLitScope) && cx.Scope.Is(BlockScope) && cx.Scope.Is(scope)) {
+			t.Errorf("Scope = %v, want FuncLitScope|BlockScope|%v in %q", cx.Scope, scope, c.src)
+		}
+	}
+}
+
+func TestEnclosingStmtRange(t *testing.T) {
+	cases := []struct {
+		src     string
+		leading bool
+		want    string
+	}{
+		{"a()\n\n\tb(‸)\n\tc()", false, "b()"},
+		{"a()\n\n\tb(‸)\n\tc()", true, "\n\tb()\n"},
+		{"a()\n\t\n\t\n\tx := ‸1\n\tc()", true, "\t\n\t\n\tx := 1\n"},
+		{"a()\n\tb(‸)\n\tc()", true, "\tb()\n"},
+		{"a(); b(‸)\n\tc()", true, "b()"},
+		{"b(‸) // note\n\tc()", true, "b()"},
+		{"if x {\n\t\t‸\n\t}", true, ""},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		pe, ok := cx.EnclosingStmtRange(c.leading)
+		got := ""
+		if ok {
+			got = string(cx.Src[cx.TokenFile.Offset(pe.Pos()):cx.TokenFile.Offset(pe.End())])
+		}
+		if got != c.want || ok != (c.want != "") {
+			t.Errorf("EnclosingStmtRange(%v) = (%q, %v), want (%q, %v) in %q", c.leading, got, ok, c.want, c.want != "", c.src)
+		}
+	}
+}
+
+func TestNearestLoopLabel(t *testing.T) {
+	cases := []struct {
+		src   string
+		label string
+		ok    bool
+	}{
+		{"Outer:\n\tfor {\n\t\t‸\n\t}", "Outer", true},
+		{"Outer:\n\tfor _, x := range xs {\n\t\tif x {\n\t\t\t‸\n\t\t}\n\t}", "Outer", true},
+		{"Outer:\n\tfor {\n\t\tfor {\n\t\t\t‸\n\t\t}\n\t}", "Outer", true},
+		{"Outer:\n\tfor {\n\t\tswitch x {\n\t\tcase 1:\n\t\t\t‸\n\t\t}\n\t}", "Outer", true},
+		{"Outer:\n\tfor {\n\tInner:\n\t\tfor {\n\t\t\t‸\n\t\t}\n\t}", "Inner", true},
+		{"for {\n\t\tfor {\n\t\t\t‸\n\t\t}\n\t}", "", true},
+		{"for {\n\t\tgo func() {\n\t\t\tfor {\n\t\t\t\t‸\n\t\t\t}\n\t\t}()\n\t}", "", true},
+		{"Sw:\n\tswitch x {\n\tcase 1:\n\t\t‸\n\t}", "Sw", true},
+		{"Sel:\n\tselect {\n\tdefault:\n\t\t‸\n\t}", "Sel", true},
+		{"for x‸ := 0; x < 1; x++ {\n\t}", "", false},
+		{"Outer:\n\tfor {\n\t\tgo func() {\n\t\t\t‸\n\t\t}()\n\t}", "", false},
+		{"‸", "", false},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		label, ok := cx.NearestLoopLabel()
+		if label != c.label || ok != c.ok {
+			t.Errorf("NearestLoopLabel() = (%q, %v), want (%q, %v) in %q", label, ok, c.label, c.ok, c.src)
+		}
+	}
+}
+
+func TestIncompleteStmtKeyword(t *testing.T) {
+	cases := []struct {
+		src   string
+		kw    token.Token
+		scope CurScope
+	}{
+		{"return ‸", token.RETURN, ReturnScope},
+		{"defer ‸", token.DEFER, DeferScope},
+		{"go ‸", token.GO, GoScope},
+		{"if x {\n\t\tgo ‸\n\t}", token.GO, GoScope},
+		{"defer mu.Unlock‸", token.DEFER, DeferScope},
+		{"go f‸", token.GO, GoScope},
+		{"return x, ‸", token.ILLEGAL, ReturnScope},
+		{"defer f(‸)", token.ILLEGAL, DeferScope},
+		{"x := ‸", token.ILLEGAL, 0},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		kw, ok := cx.IncompleteStmtKeyword()
+		if kw != c.kw || ok != (c.kw != token.ILLEGAL) {
+			t.Errorf("IncompleteStmtKeyword() = (%v, %v), want (%v, %v) in %q", kw, ok, c.kw, c.kw != token.ILLEGAL, c.src)
+		}
+		if c.scope != 0 && !cx.Scope.Is(c.scope) {
+			t.Errorf("Scope = %v, want %v in %q", cx.Scope, c.scope, c.src)
+		}
+	}
+
+	for _, src := range []string{
+		"s := `\nreturn ‸\n`",
+		"s := `\ndefer f‸\n`",
+		"/*\ngo ‸\n*/",
+		"// return ‸",
+	} {
+		cx := newTestCurCtx(t, "package p\nfunc f() {\n\t"+src+"\n}\n")
+		if kw, ok := cx.IncompleteStmtKeyword(); ok {
+			t.Errorf("IncompleteStmtKeyword() = (%v, true), want (ILLEGAL, false) in %q", kw, src)
+		}
+		if cx.Scope.Is(ReturnScope, DeferScope, GoScope) {
+			t.Errorf("Scope = %v, want it to exclude ReturnScope, DeferScope and GoScope in %q", cx.Scope, src)
+		}
+	}
+}
+
+func TestCommaOkContext(t *testing.T) {
+	cases := []struct {
+		src   string
+		value string
+		okNm  string
+		kind  CommaOkKind
+	}{
+		{"if v, ok := m[k]; ‸ {\n\t}", "v", "ok", MapIndexCommaOk},
+		{"if _, ok := x.(T); ‸ {\n\t}", "_", "ok", TypeAssertCommaOk},
+		{"if v, _ := <-ch; v != nil {\n\t\t‸\n\t}", "v", "_", ChanRecvCommaOk},
+		{"if v, found = m[k]; ‸found {\n\t}", "v", "found", MapIndexCommaOk},
+		{"switch s, ok := x.(fmt.Stringer); ‸ {\n\t}", "s", "ok", TypeAssertCommaOk},
+		{"if v, ok := m[‸k]; ok {\n\t}", "", "", UnknownCommaOk},
+		{"if a, b := f(); ‸ {\n\t}", "", "", UnknownCommaOk},
+		{"if v := m[k]; ‸ {\n\t}", "", "", UnknownCommaOk},
+		{"if v, ok := m[k]; ok {\n\t\tfunc() {\n\t\t\t‸\n\t\t}()\n\t}", "", "", UnknownCommaOk},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		value, okNm, kind, ok := cx.CommaOkContext()
+		if value != c.value || okNm != c.okNm || kind != c.kind || ok != (c.kind != UnknownCommaOk) {
+			t.Errorf("CommaOkContext() = (%q, %q, %v, %v), want (%q, %q, %v, %v) in %q", value, okNm, kind, ok, c.value, c.okNm, c.kind, c.kind != UnknownCommaOk, c.src)
+		}
+	}
+}
+
+func TestResultDiscarded(t *testing.T) {
+	decls := "package p\ntype T struct{}\nfunc (t *T) Get() int { return 0 }\nfunc (t *T) Set(v int) {}\nfunc pair() (int, error) { return 0, nil }\n"
+	cases := []struct {
+		src  string
+		call string
+	}{
+		{"t := &T{}\n\tt.Get(‸)", "t.Get()"},
+		{"var t T\n\tt.G‸et()", "t.Get()"},
+		{"pair(‸)", "pair()"},
+		{"f := func() bool { return true }\n\tf(‸)", "f()"},
+		{"var s []int\n\tappend(s, 1‸)", "append(s, 1)"},
+		{"t := &T{}\n\tt.Set(‸)", ""},
+		{"x := pair‸()", ""},
+		{"obj.Method(‸)", ""},
+		{"var s []int\n\tcopy(s, ‸)", ""},
+		{"len := func(int) {}\n\tlen(‸)", ""},
+		{"go pair(‸)", ""},
+	}
+	for _, c := range cases {
+		src := decls + "func f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		call, ok := cx.ResultDiscarded()
+		got := ""
+		if call != nil {
+			got, _ = cx.Print(call)
+		}
+		if got != c.call || ok != (c.call != "") {
+			t.Errorf("ResultDiscarded() = (`%s`, %v), want (`%s`, %v) in %q", got, ok, c.call, c.call != "", c.src)
+		}
+	}
+}
+
+func TestLoopBoundContext(t *testing.T) {
+	cases := []struct {
+		src        string
+		cond       string
+		suspicious bool
+	}{
+		{"for i := 0; i <= len(s)‸; i++ {\n\t}", "i <= len(s)", true},
+		{"for i := 0; i‸ <= cap(s); i++ {\n\t}", "i <= cap(s)", true},
+		{"for i := 0; len(s) >= i‸; i++ {\n\t}", "len(s) >= i", true},
+		{"for i := 0; i < len(s)‸; i++ {\n\t}", "i < len(s)", false},
+		{"for i := 0; i <= n‸; i++ {\n\t}", "i <= n", false},
+		{"for i := len(s) - 1; i >= 0‸; i-- {\n\t}", "i >= 0", false},
+		{"for ok‸ {\n\t}", "ok", false},
+		{"len := func([]int) int { return 0 }\n\tfor i := 0; i <= len(s)‸; i++ {\n\t}", "i <= len(s)", false},
+		{"for i := 0; i <= len(s); i++ {\n\t\t‸\n\t}", "", false},
+		{"for i := 0‸; i <= len(s); i++ {\n\t}", "", false},
+		{"for ‸{\n\t}", "", false},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f(s []int, n int, ok bool) {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		cond, suspicious, ok := cx.LoopBoundContext()
+		got := ""
+		if cond != nil {
+			got, _ = cx.Print(cond)
+		}
+		if got != c.cond || suspicious != c.suspicious || ok != (c.cond != "") {
+			t.Errorf("LoopBoundContext() = (`%s`, %v, %v), want (`%s`, %v, %v) in %q", got, suspicious, ok, c.cond, c.suspicious, c.cond != "", c.src)
+		}
+	}
+}
+
+func TestInExprStmt(t *testing.T) {
+	cases := []struct {
+		src  string
+		stmt string
+	}{
+		{"obj.Method(‸)", "obj.Method()"},
+		{"x + 1‸", "x + 1"},
+		{"f(g(‸))", "f(g())"},
+		{"go func() {\n\t\tg(‸)\n\t}()", "g()"},
+		{"<-ch‸", ""},
+		{"(<-ch‸)", ""},
+		{"v := <-ch‸", ""},
+		{"x := f(‸)", ""},
+		{"go f(‸)", ""},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		es, ok := cx.InExprStmt()
+		got := ""
+		if es != nil {
+			got, _ = cx.Print(es)
+		}
+		if got != c.stmt || ok != (c.stmt != "") {
+			t.Errorf("InExprStmt() = (`%s`, %v), want (`%s`, %v) in %q", got, ok, c.stmt, c.stmt != "", c.src)
+		}
+	}
+}
+
+func TestGotoWouldSkipDecls(t *testing.T) {
+	cases := []struct {
+		src   string
+		label string
+		skips bool
+	}{
+		{"goto L‸\n\tx := 1\n\t_ = x\nL:\n\tprintln()", "L", true},
+		{"goto L‸\n\tvar x int\n\t_ = x\nL:\n\tprintln()", "L", true},
+		{"goto L‸\n\tconst c = 1\nL:\n\tprintln()", "L", false},
+		{"goto L‸\n\tprintln()\nL:\n\tprintln()", "L", false},
+		{"goto L‸\n\t{\n\t\tx := 1\n\t\t_ = x\n\t}\nL:\n\tprintln()", "L", false},
+		{"L:\n\tx := 1\n\t_ = x\n\tgoto L‸", "L", false},
+		{"if true {\n\t\tgoto L‸\n\t}\n\tx := 1\n\t_ = x\nL:\n\tprintln()", "L", true},
+		{"x := 1\n\t_ = x\n\tif true {\n\t\tgoto L‸\n\t}\nL:\n\tprintln()", "L", false},
+		{"goto M‸\n\tx := 1\n\t_ = x\nL:\n\tprintln()", "M", false},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		if got := cx.GotoWouldSkipDecls(c.label); got != c.skips {
+			t.Errorf("GotoWouldSkipDecls(%q) = %v, want %v in %q", c.label, got, c.skips, c.src)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package cursor
+
+import (
+	"bytes"
+	"go/token"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// StringContextMatcher classifies the string literal the cursor is in.
+// If ok is true, the returned scope is added to CurCtx.Scope.
+type StringContextMatcher func(cx *CurCtx) (scope CurScope, ok bool)
+
+var (
+	stringContexts = struct {
+		sync.RWMutex
+		l []StringContextMatcher
+	}{}
+)
+
+// RegisterStringContext registers matcher to classify string literals embedding a DSL e.g. SQL.
+//
+// It's called for each new CurCtx in StringScope.
+// The scope it returns is usually allocated using RegisterScope.
+func RegisterStringContext(matcher StringContextMatcher) {
+	stringContexts.Lock()
+	defer stringContexts.Unlock()
+
+	stringContexts.l = append(stringContexts.l, matcher)
+}
+
+// CallStringContext returns a StringContextMatcher that matches string literals passed directly
+// to a function call whose callee, followed by `(`, matches pat e.g. `\.(Query|Exec)(Context)?\($`.
+func CallStringContext(pat *regexp.Regexp, scope CurScope) StringContextMatcher {
+	return func(cx *CurCtx) (CurScope, bool) {
+		lit := cx.BasicLit
+		call := cx.enclosingCallArgs()
+		if lit == nil || call == nil {
+			return 0, false
+		}
+		for _, a := range call.Args {
+			if a != lit {
+				continue
+			}
+			fn, err := cx.Print(call.Fun)
+			if err == nil && pat.MatchString(fn+"(") {
+				return scope, true
+			}
+		}
+		return 0, false
+	}
+}
+
+// RawStringLineContext returns the text of the line of the raw string literal the cursor is in,
+// and the byte offset of the cursor in that line e.g. (`WHERE id = ?`, 6) in "`SELECT *\nWHERE ‸id = ?`".
+//
+// The line is bounded by the literal's backquotes, so the first and last lines exclude them.
+// Tabs are not expanded, and the `\r` of CRLF line endings is excluded from lineText.
+func (cx *CurCtx) RawStringLineContext() (lineText string, offsetInLine int, ok bool) {
+	lit := cx.BasicLit
+	if lit == nil || lit.Kind != token.STRING || !strings.HasPrefix(lit.Value, "`") || !cx.Scope.Is(StringScope) {
+		return "", 0, false
+	}
+	start := cx.TokenFile.Offset(lit.Pos()) + 1
+	end := cx.TokenFile.Offset(lit.End())
+	if len(lit.Value) >= 2 && strings.HasSuffix(lit.Value, "`") {
+		end--
+	}
+	if cx.srcPos < start || cx.srcPos > end {
+		return "", 0, false
+	}
+	ls := lineStart(cx.Src, cx.srcPos)
+	if ls < start {
+		ls = start
+	}
+	le := end
+	if i := bytes.IndexByte(cx.Src[cx.srcPos:end], '\n'); i >= 0 {
+		le = cx.srcPos + i
+	}
+	line := bytes.TrimSuffix(cx.Src[ls:le], []byte{'\r'})
+	return string(line), cx.srcPos - ls, true
+}
+
+// stringContextScope returns the union of scopes returned by the registered StringContextMatchers
+func (cx *CurCtx) stringContextScope() CurScope {
+	stringContexts.RLock()
+	l := stringContexts.l
+	stringContexts.RUnlock()
+
+	scope := CurScope(0)
+	for _, m := range l {
+		if cs, ok := m(cx); ok {
+			scope |= cs
+		}
+	}
+	return scope
+}
@@ -0,0 +1,89 @@
+package cursor
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestRegisterStringContext(t *testing.T) {
+	sqlScope := RegisterScope("SQLScope")
+	RegisterStringContext(CallStringContext(regexp.MustCompile(`\.(Query|Exec)(Context)?\($`), sqlScope))
+
+	if s := sqlScope.String(); s != "SQLScope" {
+		t.Errorf("RegisterScope().String() = %q, want %q", s, "SQLScope")
+	}
+
+	cases := []struct {
+		src string
+		sql bool
+	}{
+		{"package p\nfunc f() {\n\tdb.Query(\"SELECT ‸\")\n}\n", true},
+		{"package p\nfunc f() {\n\tdb.QueryContext(ctx, `SELECT ‸`, x)\n}\n", true},
+		{"package p\nfunc f() {\n\ttx.Exec(\"‸\")\n}\n", true},
+		{"package p\nfunc f() {\n\tdb.QueryRow(\"SELECT ‸\")\n}\n", false},
+		{"package p\nfunc f() {\n\tfmt.Println(\"SELECT ‸\")\n}\n", false},
+		{"package p\nfunc f() {\n\tdb.Query(q(\"SELECT ‸\"))\n}\n", false},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		if got := cx.Scope.Is(sqlScope); got != c.sql {
+			t.Errorf("Scope.Is(SQLScope) = %v, want %v in %q", got, c.sql, c.src)
+		}
+	}
+}
+
+func TestRegisterScopeExhausted(t *testing.T) {
+	customScopes.Lock()
+	end, full, names := customScopes.end, customScopes.full, map[CurScope]string{}
+	for k, v := range customScopes.names {
+		names[k] = v
+	}
+	customScopes.Unlock()
+	defer func() {
+		customScopes.Lock()
+		customScopes.end, customScopes.full, customScopes.names = end, full, names
+		customScopes.Unlock()
+	}()
+
+	var last CurScope
+	func() {
+		defer func() { recover() }()
+		for {
+			last = RegisterScope("LastScope")
+		}
+	}()
+
+	if last != 1<<63 {
+		t.Errorf("last RegisterScope() = %#x, want %#x", uint64(last), uint64(1<<63))
+	}
+	if s := StringScope.String(); s != "StringScope" {
+		t.Errorf("StringScope.String() = %q, want %q", s, "StringScope")
+	}
+	if s := last.String(); s != "LastScope" {
+		t.Errorf("RegisterScope().String() = %q, want %q", s, "LastScope")
+	}
+}
+
+func TestRawStringLineContext(t *testing.T) {
+	cases := []struct {
+		src    string
+		line   string
+		offset int
+		ok     bool
+	}{
+		{"`SELECT *\nWHERE ‸id = ?`", "WHERE id = ?", 6, true},
+		{"`SELECT ‸*\nFROM t`", "SELECT *", 7, true},
+		{"`SELECT *\r\nFROM t‸\r\nWHERE x`", "FROM t", 6, true},
+		{"`{{range .}}\n\t{{.‸Name}}\n{{end}}`", "\t{{.Name}}", 4, true},
+		{"`\n‸\n`", "", 0, true},
+		{"\"SELECT ‸*\"", "", 0, false},
+	}
+	for _, c := range cases {
+		src := "package p\nvar q = " + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		line, offset, ok := cx.RawStringLineContext()
+		if line != c.line || offset != c.offset || ok != c.ok {
+			t.Errorf("RawStringLineContext() = (%q, %d, %v), want (%q, %d, %v) in %q", line, offset, ok, c.line, c.offset, c.ok, c.src)
+		}
+	}
+}
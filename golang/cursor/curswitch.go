@@ -0,0 +1,118 @@
+package cursor
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// TypeSwitchBinding returns the name of the variable bound by the type switch enclosing the cursor
+// e.g. `v` in `switch v := x.(type) { case T: ‸ }`.
+// ok is true iff the cursor is in a type switch; name is empty for the unnamed form `switch x.(type)`.
+func (cx *CurCtx) TypeSwitchBinding() (name string, ok bool) {
+	var ts *ast.TypeSwitchStmt
+	if !cx.Set(&ts) {
+		return "", false
+	}
+	if asn, ok := ts.Assign.(*ast.AssignStmt); ok && len(asn.Lhs) == 1 {
+		if id, ok := asn.Lhs[0].(*ast.Ident); ok && id.Name != "_" {
+			return id.Name, true
+		}
+	}
+	return "", true
+}
+
+// TypeSwitchCaseType returns the type of the type switch case clause whose body encloses the cursor
+// e.g. `*Foo` in `switch v := x.(type) { case *Foo: ‸ }`, i.e. the type of the binding in that body.
+//
+// ok is false for `default`, `case nil` and multi-type cases e.g. `case A, B:`
+// because the binding then has the type of the switched expression.
+func (cx *CurCtx) TypeSwitchCaseType() (typ ast.Expr, ok bool) {
+	for i := len(cx.Nodes) - 1; i >= 0; i-- {
+		ts, _ := cx.Nodes[i].(*ast.TypeSwitchStmt)
+		if ts == nil {
+			continue
+		}
+		clause, _ := cx.clauseAt(ts.Body).(*ast.CaseClause)
+		if clause == nil || len(clause.List) != 1 {
+			return nil, false
+		}
+		if id, ok := clause.List[0].(*ast.Ident); ok && id.Name == "nil" {
+			return nil, false
+		}
+		return clause.List[0], true
+	}
+	return nil, false
+}
+
+// CaseExprIndex returns the case clause whose expression list encloses the cursor,
+// and the index of the expression the cursor is on e.g. 2 in `case A, B, ‸:`.
+//
+// The index is computed from the source, as for EnclosingCall, so it's robust to trailing commas.
+// For a `default` clause, clause is returned, but ok is false because it has no expressions.
+func (cx *CurCtx) CaseExprIndex() (clause *ast.CaseClause, index int, ok bool) {
+	if !cx.Set(&clause) || cx.TokenPos < clause.Case || cx.TokenPos > clause.Colon {
+		return nil, 0, false
+	}
+	if clause.List == nil {
+		return clause, 0, false
+	}
+	start := cx.TokenFile.Offset(clause.Case) + len(token.CASE.String())
+	if cx.srcPos <= start {
+		return nil, 0, false
+	}
+	return clause, callArgIndex(cx.Src[start:cx.srcPos]), true
+}
+
+// IsBooleanSwitch returns true if the switch statement enclosing the cursor has no tag
+// e.g. `switch { case ‸ }`, so its case expressions are boolean conditions.
+// ok is false if the cursor is not in a switch or type switch statement.
+func (cx *CurCtx) IsBooleanSwitch() (isBool bool, ok bool) {
+	for i := len(cx.Nodes) - 1; i >= 0; i-- {
+		switch x := cx.Nodes[i].(type) {
+		case *ast.SwitchStmt:
+			return x.Tag == nil, true
+		case *ast.TypeSwitchStmt:
+			return false, true
+		case *ast.FuncLit:
+			return false, false
+		}
+	}
+	return false, false
+}
+
+// SwitchHasDefault returns whether the switch, type switch or select statement enclosing the cursor
+// has a `default` clause. ok is false if the cursor is not in one of these statements.
+func (cx *CurCtx) SwitchHasDefault() (hasDefault bool, ok bool) {
+	for i := len(cx.Nodes) - 1; i >= 0; i-- {
+		var body *ast.BlockStmt
+		switch x := cx.Nodes[i].(type) {
+		case *ast.SwitchStmt:
+			body = x.Body
+		case *ast.TypeSwitchStmt:
+			body = x.Body
+		case *ast.SelectStmt:
+			body = x.Body
+		case *ast.FuncLit:
+			return false, false
+		default:
+			continue
+		}
+		if body == nil {
+			return false, true
+		}
+		for _, s := range body.List {
+			switch x := s.(type) {
+			case *ast.CaseClause:
+				if x.List == nil {
+					return true, true
+				}
+			case *ast.CommClause:
+				if x.Comm == nil {
+					return true, true
+				}
+			}
+		}
+		return false, true
+	}
+	return false, false
+}
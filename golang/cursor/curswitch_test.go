@@ -0,0 +1,138 @@
+package cursor
+
+import (
+	"testing"
+)
+
+func TestCaseExprIndex(t *testing.T) {
+	cases := []struct {
+		src    string
+		index  int
+		ok     bool
+		clause bool
+	}{
+		{"case ‸A:", 0, true, true},
+		{"case A‸:", 0, true, true},
+		{"case A, ‸B:", 1, true, true},
+		{"case A, B, C‸:", 2, true, true},
+		{"case A, B,‸:", 2, true, true},
+		{"case A, B, ‸:", 2, true, true},
+		{"case f(a, b), ‸:", 1, true, true},
+		{"case A /* , */, ‸:", 1, true, true},
+		{"default‸:", 0, false, true},
+		{"case A:\n\t\t‸", 0, false, false},
+		{"c‸ase A:", 0, false, false},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\tswitch x {\n\t" + c.src + "\n\t}\n}\n"
+		cx := newTestCurCtx(t, src)
+		clause, index, ok := cx.CaseExprIndex()
+		if index != c.index || ok != c.ok || (clause != nil) != c.clause {
+			t.Errorf("CaseExprIndex() = (%v, %d, %v), want (%v, %d, %v) in %q", clause != nil, index, ok, c.clause, c.index, c.ok, c.src)
+		}
+	}
+}
+
+func TestTypeSwitchCaseType(t *testing.T) {
+	cases := []struct {
+		src string
+		typ string
+		ok  bool
+	}{
+		{"case *Foo:\n\t\tv.‸", "*Foo", true},
+		{"case []int:\n\t\t‸", "[]int", true},
+		{"case io.Reader:\n\t\tif v != nil {\n\t\t\t‸\n\t\t}", "io.Reader", true},
+		{"case int:\n\t\tswitch {\n\t\tcase true:\n\t\t\t‸\n\t\t}", "int", true},
+		{"case A, B:\n\t\t‸", "", false},
+		{"case nil:\n\t\t‸", "", false},
+		{"default:\n\t\t‸", "", false},
+		{"case ‸Foo:", "", false},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\tswitch v := x.(type) {\n\t" + c.src + "\n\t}\n}\n"
+		cx := newTestCurCtx(t, src)
+		typ, ok := cx.TypeSwitchCaseType()
+		s := ""
+		if typ != nil {
+			s, _ = cx.Print(typ)
+		}
+		if s != c.typ || ok != c.ok {
+			t.Errorf("TypeSwitchCaseType() = (`%s`, %v), want (`%s`, %v) in %q", s, ok, c.typ, c.ok, c.src)
+		}
+	}
+}
+
+func TestIsBooleanSwitch(t *testing.T) {
+	cases := []struct {
+		src    string
+		isBool bool
+		ok     bool
+	}{
+		{"switch {\n\tcase ‸:\n\t}", true, true},
+		{"switch x := f(); {\n\tcase x > 0:\n\t\t‸\n\t}", true, true},
+		{"switch x {\n\tcase ‸:\n\t}", false, true},
+		{"switch x := f(); x {\n\tcase ‸:\n\t}", false, true},
+		{"switch x.(type) {\n\tcase ‸:\n\t}", false, true},
+		{"switch {\n\tcase x:\n\t\tfunc() {\n\t\t\t‸\n\t\t}()\n\t}", false, false},
+		{"if x {\n\t\t‸\n\t}", false, false},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		isBool, ok := cx.IsBooleanSwitch()
+		if isBool != c.isBool || ok != c.ok {
+			t.Errorf("IsBooleanSwitch() = (%v, %v), want (%v, %v) in %q", isBool, ok, c.isBool, c.ok, c.src)
+		}
+	}
+}
+
+func TestSwitchHasDefault(t *testing.T) {
+	cases := []struct {
+		src        string
+		hasDefault bool
+		ok         bool
+	}{
+		{"switch x {\n\tcase 1:\n\t\t‸\n\t}", false, true},
+		{"switch x {\n\tcase 1:\n\t\t‸\n\tdefault:\n\t}", true, true},
+		{"switch {\n\t‸\n\t}", false, true},
+		{"switch v := x.(type) {\n\tdefault:\n\t\t‸\n\t}", true, true},
+		{"select {\n\tcase <-ch:\n\t\t‸\n\t}", false, true},
+		{"select {\n\tcase <-ch:\n\tdefault:\n\t\t‸\n\t}", true, true},
+		{"switch x {\n\tdefault:\n\t\tswitch y {\n\t\tcase 1:\n\t\t\t‸\n\t\t}\n\t}", false, true},
+		{"switch x {\n\tdefault:\n\t\tf := func() {\n\t\t\t‸\n\t\t}\n\t}", false, false},
+		{"‸", false, false},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f() {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		hasDefault, ok := cx.SwitchHasDefault()
+		if hasDefault != c.hasDefault || ok != c.ok {
+			t.Errorf("SwitchHasDefault() = (%v, %v), want (%v, %v) in %q", hasDefault, ok, c.hasDefault, c.ok, c.src)
+		}
+	}
+}
+
+func TestTypeSwitchBinding(t *testing.T) {
+	cases := []struct {
+		src  string
+		name string
+		ok   bool
+	}{
+		{"switch v := x.(type) {\n\tcase int:\n\t\t‸\n\t}", "v", true},
+		{"switch v := x.(type) {\n\tcase int:\n\t\t_ = v\n\tdefault:\n\t\t‸\n\t}", "v", true},
+		{"switch x.(type) {\n\tcase int:\n\t\t‸\n\t}", "", true},
+		{"switch _ := x.(type) {\n\tcase int:\n\t\t‸\n\t}", "", true},
+		{"switch v‸ := x.(type) {\n\tcase int:\n\t}", "v", true},
+		{"switch v := x.(ty‸pe) {\n\tcase int:\n\t}", "v", true},
+		{"switch x {\n\tcase 1:\n\t\t‸\n\t}", "", false},
+		{"‸", "", false},
+	}
+	for _, c := range cases {
+		src := "package p\nfunc f(x interface{}) {\n\t" + c.src + "\n}\n"
+		cx := newTestCurCtx(t, src)
+		name, ok := cx.TypeSwitchBinding()
+		if name != c.name || ok != c.ok {
+			t.Errorf("TypeSwitchBinding() = (%q, %v), want (%q, %v) in %q", name, ok, c.name, c.ok, c.src)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package cursor
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+var (
+	// predeclaredTypes is the set of Go's predeclared type names
+	predeclaredTypes = map[string]bool{
+		"any":        true,
+		"bool":       true,
+		"byte":       true,
+		"comparable": true,
+		"complex64":  true,
+		"complex128": true,
+		"error":      true,
+		"float32":    true,
+		"float64":    true,
+		"int":        true,
+		"int8":       true,
+		"int16":      true,
+		"int32":      true,
+		"int64":      true,
+		"rune":       true,
+		"string":     true,
+		"uint":       true,
+		"uint8":      true,
+		"uint16":     true,
+		"uint32":     true,
+		"uint64":     true,
+		"uintptr":    true,
+	}
+
+	// predeclaredFuncs is the set of Go's builtin function names
+	predeclaredFuncs = map[string]bool{
+		"append":  true,
+		"cap":     true,
+		"clear":   true,
+		"close":   true,
+		"complex": true,
+		"copy":    true,
+		"delete":  true,
+		"imag":    true,
+		"len":     true,
+		"make":    true,
+		"max":     true,
+		"min":     true,
+		"new":     true,
+		"panic":   true,
+		"print":   true,
+		"println": true,
+		"real":    true,
+		"recover": true,
+	}
+)
+
+// isTypeExpr returns true if x is syntactically a type
+// i.e. a composite type expression or a predeclared type name
+func isTypeExpr(x ast.Expr) bool {
+	switch x := x.(type) {
+	case *ast.ArrayType, *ast.MapType, *ast.ChanType, *ast.FuncType, *ast.StructType, *ast.InterfaceType:
+		return true
+	case *ast.ParenExpr:
+		if _, ok := x.X.(*ast.StarExpr); ok {
+			return true
+		}
+		return isTypeExpr(x.X)
+	case *ast.Ident:
+		return predeclaredTypes[x.Name]
+	}
+	return false
+}
+
+// LocalTypeNames returns the names of the types declared in the file,
+// including generic types, local types and type params that are in scope at the cursor.
+// Predeclared types are not included.
+func (cx *CurCtx) LocalTypeNames() []string {
+	names := []string{}
+	seen := map[string]bool{}
+	add := func(id *ast.Ident) {
+		if id == nil || id.Name == "_" || seen[id.Name] {
+			return
+		}
+		seen[id.Name] = true
+		names = append(names, id.Name)
+	}
+
+	if cx.AstFile != nil {
+		for _, d := range cx.AstFile.Decls {
+			if gd, ok := d.(*ast.GenDecl); ok && gd.Tok == token.TYPE {
+				for _, d := range genDeclDecls(gd) {
+					add(d.Name)
+				}
+			}
+		}
+	}
+	for _, n := range cx.Nodes {
+		if ts, ok := n.(*ast.TypeSpec); ok && ts.TypeParams != nil {
+			for _, f := range ts.TypeParams.List {
+				for _, id := range f.Names {
+					add(id)
+				}
+			}
+		}
+	}
+	for _, d := range cx.localDecls() {
+		if d.Tok == token.TYPE {
+			add(d.Name)
+		}
+	}
+	return names
+}
+
+// ExpectsType returns true if the cursor is in a position where a type is expected
+// e.g. `var x ‸`, `func(a ‸)`, `struct{ F ‸ }`, `x.(‸)` or `map[‸]T`
+func (cx *CurCtx) ExpectsType() bool {
+	for i := len(cx.Nodes) - 1; i >= 1; i-- {
+		if yes, decided := typeSlot(cx.Nodes[i-1], cx.Nodes[i]); decided {
+			return yes
+		}
+	}
+	return false
+}
+
+// typeSlot reports whether child is in the type position of parent.
+// decided is false if parent doesn't decide whether a type is expected e.g. a SelectorExpr in `pkg.T`.
+func typeSlot(parent, child ast.Node) (yes bool, decided bool) {
+	switch x := parent.(type) {
+	case *ast.Field:
+		return child == x.Type, true
+	case *ast.ValueSpec:
+		return child == x.Type, true
+	case *ast.TypeSpec:
+		return child == x.Type, true
+	case *ast.ArrayType:
+		return child == x.Elt, true
+	case *ast.MapType:
+		return child == x.Key || child == x.Value, true
+	case *ast.ChanType:
+		return child == x.Value, true
+	case *ast.TypeAssertExpr:
+		return child == x.Type, true
+	case *ast.CompositeLit:
+		return child == x.Type, true
+	case *ast.FieldList, *ast.StructType, *ast.InterfaceType, *ast.FuncType, *ast.BlockStmt:
+		// the cursor is between fields or statements
+		return false, true
+	}
+	return false, false
+}
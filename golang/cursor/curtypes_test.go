@@ -0,0 +1,54 @@
+package cursor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpectsType(t *testing.T) {
+	cases := []struct {
+		src  string
+		want bool
+	}{
+		{"package p\nvar x i‸\n", true},
+		{"package p\nvar x = i‸\n", false},
+		{"package p\nfunc f(a i‸) {}\n", true},
+		{"package p\nfunc f(a‸ int) {}\n", false},
+		{"package p\ntype S struct {\n\tF i‸\n}\n", true},
+		{"package p\ntype S struct {\n\tF‸ int\n}\n", false},
+		{"package p\ntype S struct {\n\t‸\n}\n", false},
+		{"package p\nvar x [N‸]int\n", false},
+		{"package p\nvar x map[str‸]int\n", true},
+		{"package p\nvar x pkg.T‸\n", true},
+		{"package p\nfunc f() {\n\t_ = x.(T‸)\n}\n", true},
+		{"package p\nfunc f() {\n\tx := T{F: v‸}\n}\n", false},
+	}
+	for _, c := range cases {
+		cx := newTestCurCtx(t, c.src)
+		if got := cx.ExpectsType(); got != c.want {
+			t.Errorf("ExpectsType() = %v, want %v in %q", got, c.want, c.src)
+		}
+	}
+}
+
+func TestLocalTypeNames(t *testing.T) {
+	decls := "package p\ntype A int\ntype (\n\tB struct{}\n\tC = B\n\t_ int\n)\ntype List[T any] []T\nvar v int\nfunc g() {\n\ttype Hidden int\n}\n"
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"func f() {\n\t‸\n}", "A B C List"},
+		{"func f() {\n\ttype L int\n\ttype (\n\t\tM struct{}\n\t)\n\t‸\n}", "A B C List L M"},
+		{"func f() {\n\t‸\n\ttype L int\n}", "A B C List"},
+		{"func f[K comparable, V any]() {\n\t‸\n}", "A B C List K V"},
+		{"type Map[K comparable, V any] struct {\n\tm map[K]‸\n}", "A B C List Map K V"},
+		{"func (l List[E]) f() {\n\t‸\n}", "A B C List E"},
+	}
+	for _, c := range cases {
+		src := decls + c.src + "\n"
+		cx := newTestCurCtx(t, src)
+		if got := strings.Join(cx.LocalTypeNames(), " "); got != c.want {
+			t.Errorf("LocalTypeNames() = `%s`, want `%s` in %q", got, c.want, c.src)
+		}
+	}
+}